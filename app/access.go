@@ -0,0 +1,438 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mctui/client"
+	"mctui/colors"
+	"mctui/mcformat"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A player or an IP in one of the lists
+type accessEntry struct {
+	name string
+	// Set for bans
+	source string
+	reason string
+}
+
+func (e accessEntry) Title() string { return e.name }
+func (e accessEntry) Description() string {
+	if e.source == "" {
+		return ""
+	}
+	return fmt.Sprintf("banned by %s: %s", e.source, e.reason)
+}
+func (e accessEntry) FilterValue() string { return e.name }
+
+// A tab of the player management screen
+type accessTab struct {
+	title string
+	// Command that lists the entries, empty if there is none
+	list   string
+	parse  func(string) []accessEntry
+	add    string
+	remove string
+	// Shown while adding
+	placeholder string
+}
+
+var accessTabs = []accessTab{
+	{
+		title:       "Whitelist",
+		list:        "whitelist list",
+		parse:       parseWhitelist,
+		add:         "whitelist add %s",
+		remove:      "whitelist remove %s",
+		placeholder: "player",
+	},
+	{
+		// Vanilla has no command to list them
+		title:       "Operators",
+		add:         "op %s",
+		remove:      "deop %s",
+		placeholder: "player",
+	},
+	{
+		title:       "Banned players",
+		list:        "banlist players",
+		parse:       parseBanlist,
+		add:         "ban %s",
+		remove:      "pardon %s",
+		placeholder: "player [reason]",
+	},
+	{
+		title:       "Banned IPs",
+		list:        "banlist ips",
+		parse:       parseBanlist,
+		add:         "ban-ip %s",
+		remove:      "pardon-ip %s",
+		placeholder: "ip or player [reason]",
+	},
+}
+
+// There are 2 whitelisted player(s): alice, bob
+// There are no whitelisted players
+func parseWhitelist(output string) []accessEntry {
+	var entries []accessEntry
+	_, after, found := strings.Cut(output, ":")
+	if !found {
+		return entries
+	}
+	for _, name := range strings.Split(after, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			entries = append(entries, accessEntry{name: name})
+		}
+	}
+	return entries
+}
+
+// Names, IPv4 or IPv6 addresses. IPv6 has at least two colons
+var banRegex = regexp.MustCompile(`(\d{1,3}(?:\.\d{1,3}){3}|[0-9A-Fa-f]*:[0-9A-Fa-f]*:[0-9A-Fa-f:]*|[A-Za-z0-9_]{1,16}) was banned by (\S+?): `)
+
+// Its colon would be taken for an IPv6 address when the lines are glued
+var banHeaderRegex = regexp.MustCompile(`^\s*There are \d+ ban\(s\):`)
+
+// There are 2 ban(s):
+// alice was banned by Server: Banned by an operator.
+// 1.2.3.4 was banned by admin: griefing
+//
+// Some servers send the lines without newlines, so the reason
+// ends where the next ban starts
+func parseBanlist(output string) []accessEntry {
+	var entries []accessEntry
+	output = banHeaderRegex.ReplaceAllString(output, "")
+	matches := banRegex.FindAllStringSubmatchIndex(output, -1)
+	for i, match := range matches {
+		end := len(output)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		entries = append(entries, accessEntry{
+			name:   output[match[2]:match[3]],
+			source: output[match[4]:match[5]],
+			reason: strings.TrimSpace(output[match[1]:end]),
+		})
+	}
+	return entries
+}
+
+var accessKeys = []key.Binding{
+	key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "next list")),
+	key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "add")),
+	key.NewBinding(key.WithKeys("x", "delete"), key.WithHelp("x", "remove")),
+	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+}
+
+// Whitelist, operators and bans, shown with F2 or !players
+type accessModel struct {
+	list      list.Model
+	active    int
+	entries   [][]accessEntry
+	loaded    []bool
+	jwtToken  string
+	prevModel tea.Model
+	width     int
+	height    int
+	// Name being typed, shown with a
+	input  textinput.Model
+	adding bool
+	// Command waiting for y/n
+	confirm string
+	// Result of the last change or fetch
+	status string
+	failed bool
+	// Shown when the session expires
+	reauth screenReauth
+}
+
+// Send with the entries of a tab
+type accessListMsg struct {
+	tab     int
+	entries []accessEntry
+	err     error
+}
+
+// Send after a command that changes a list
+type accessChangedMsg struct {
+	tab     int
+	command string
+	msg     tea.Msg
+}
+
+func InitialAccessModel(prevModel tea.Model, jwtToken string, width, height int) accessModel {
+	input := textinput.New()
+	input.Prompt = "add "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(colors.Pink)
+	input.PlaceholderStyle = lipgloss.NewStyle().Foreground(colors.Surface1)
+
+	m := accessModel{
+		list:      list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		entries:   make([][]accessEntry, len(accessTabs)),
+		loaded:    make([]bool, len(accessTabs)),
+		prevModel: prevModel,
+		jwtToken:  jwtToken,
+		width:     width,
+		height:    height,
+		input:     input,
+	}
+	m.list.SetShowTitle(false)
+	m.list.AdditionalShortHelpKeys = func() []key.Binding { return accessKeys }
+	m.list.AdditionalFullHelpKeys = func() []key.Binding { return accessKeys }
+	return m
+}
+
+func (m accessModel) Init() tea.Cmd {
+	return tea.Batch(
+		requestAccessList(0, m.jwtToken),
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+func (m accessModel) tab() accessTab {
+	return accessTabs[m.active]
+}
+
+func (m accessModel) items() []list.Item {
+	var items []list.Item
+	for _, e := range m.entries[m.active] {
+		items = append(items, e)
+	}
+	return items
+}
+
+func (m accessModel) switchTab(tab int) (accessModel, tea.Cmd) {
+	m.active = (tab + len(accessTabs)) % len(accessTabs)
+	m.list.ResetFilter()
+	m.list.Select(0)
+	m.status = ""
+	cmds := []tea.Cmd{m.list.SetItems(m.items())}
+	if !m.loaded[m.active] && m.tab().list != "" {
+		cmds = append(cmds, requestAccessList(m.active, m.jwtToken))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m accessModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Ask the password here, the replies come back to this screen
+	if next, cmd, ok := m.reauth.update(msg, &m.prevModel, &m.jwtToken); ok {
+		if next == nil {
+			next = m
+		}
+		return next, cmd
+	}
+
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.confirm != "" {
+			switch strings.ToLower(msg.String()) {
+			case "y":
+				command := m.confirm
+				m.confirm = ""
+				m.status = fmt.Sprintf("Running %s...", command)
+				m.failed = false
+				return m, requestAccessChange(m.active, command, m.jwtToken)
+			case "n", "esc":
+				m.confirm = ""
+			}
+			return m, nil
+		}
+		if m.adding {
+			switch msg.Type {
+			case tea.KeyEnter:
+				if value := strings.TrimSpace(m.input.Value()); value != "" {
+					m.confirm = fmt.Sprintf(m.tab().add, value)
+				}
+				m.adding = false
+				return m, nil
+			case tea.KeyEscape:
+				m.adding = false
+				return m, nil
+			}
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+		// Keys belong to the filter input while typing
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "esc":
+			if m.list.FilterState() == list.Unfiltered {
+				return m.prevModel.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			}
+		case "tab":
+			return m.switchTab(m.active + 1)
+		case "shift+tab":
+			return m.switchTab(m.active - 1)
+		case "r":
+			if m.tab().list != "" {
+				return m, requestAccessList(m.active, m.jwtToken)
+			}
+			return m, nil
+		case "a":
+			m.adding = true
+			m.input.Placeholder = m.tab().placeholder
+			m.input.SetValue("")
+			return m, m.input.Focus()
+		case "x", "delete":
+			if e, ok := m.list.SelectedItem().(accessEntry); ok {
+				m.confirm = fmt.Sprintf(m.tab().remove, e.name)
+			}
+			return m, nil
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.input.Width = msg.Width - 10
+		h, v := docStyle.GetFrameSize()
+		// Tabs and status line
+		m.list.SetSize(msg.Width-h, msg.Height-v-3)
+	case accessListMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Can't fetch the %s: %v", strings.ToLower(accessTabs[msg.tab].title), msg.err)
+			m.failed = true
+			return m, nil
+		}
+		m.entries[msg.tab] = msg.entries
+		m.loaded[msg.tab] = true
+		if msg.tab == m.active {
+			return m, m.list.SetItems(m.items())
+		}
+		return m, nil
+	case accessChangedMsg:
+		return m.changed(msg)
+	case backgroundMsg:
+		m.prevModel, cmd = m.prevModel.Update(msg)
+		return m, cmd
+	}
+
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// Shows the result, keeps it in the history and loads the list again
+func (m accessModel) changed(msg accessChangedMsg) (tea.Model, tea.Cmd) {
+	entry, ok := historyEntry(msg.msg)
+	if !ok {
+		return m.Update(msg.msg)
+	}
+	m.prevModel, _ = m.prevModel.Update(entry)
+	m.status = mcformat.Strip(entry.output)
+	m.failed = entry.failed
+	if entry.failed {
+		return m, nil
+	}
+
+	tab := accessTabs[msg.tab]
+	if tab.list != "" {
+		return m, requestAccessList(msg.tab, m.jwtToken)
+	}
+	// Keep track of the operators changed here
+	fields := strings.Fields(msg.command)
+	name := fields[len(fields)-1]
+	// Cloned, the copies of this model share the old array
+	ops := slices.DeleteFunc(slices.Clone(m.entries[msg.tab]), func(e accessEntry) bool { return e.name == name })
+	if fields[0] == "op" {
+		ops = append(ops, accessEntry{name: name})
+	}
+	m.entries[msg.tab] = ops
+	if msg.tab == m.active {
+		return m, m.list.SetItems(m.items())
+	}
+	return m, nil
+}
+
+func (m accessModel) tabsView() string {
+	var tabs []string
+	for i, tab := range accessTabs {
+		style := lipgloss.NewStyle().Foreground(colors.Surface2)
+		if i == m.active {
+			style = style.Foreground(colors.Pink).Bold(true).Underline(true)
+		}
+		tabs = append(tabs, style.Render(tab.title))
+	}
+	separator := lipgloss.NewStyle().Foreground(colors.Surface1).Render(" │ ")
+	return strings.Join(tabs, separator)
+}
+
+func (m accessModel) statusView() string {
+	style := lipgloss.NewStyle().MaxWidth(m.width - 4).MaxHeight(1)
+	switch {
+	case m.confirm != "":
+		return style.Foreground(colors.Pink).Bold(true).Render(fmt.Sprintf("Run %s? (y/n)", m.confirm))
+	case m.adding:
+		return m.input.View()
+	case m.status != "" && m.failed:
+		return style.Foreground(colors.Red).Render(m.status)
+	case m.status != "":
+		return style.Foreground(colors.Surface2).Render(strings.ReplaceAll(m.status, "\n", " "))
+	case m.tab().list == "":
+		return style.Foreground(colors.Surface2).Render("The server can't list the operators, only the ones changed here are shown")
+	}
+	return ""
+}
+
+func (m accessModel) View() string {
+	if view, ok := m.reauth.view(m.width, m.height); ok {
+		return view
+	}
+	both := lipgloss.JoinVertical(lipgloss.Left, m.tabsView(), "", m.list.View(), m.statusView())
+	return docStyle.Render(both)
+}
+
+// ///////////////
+// HTTP requests
+// ///////////////
+
+func requestAccessList(tab int, jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		command := accessTabs[tab].list
+		output, err := api.Command(jwtToken, command)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: command,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestAccessList(tab, jwtToken)
+				},
+			}
+		}
+		if err != nil {
+			log.Printf("Can't fetch %s: %v", command, err)
+			return accessListMsg{tab: tab, err: fmt.Errorf("%s", errorText(err))}
+		}
+		return accessListMsg{tab: tab, entries: accessTabs[tab].parse(mcformat.Strip(output))}
+	}
+}
+
+func requestAccessChange(tab int, command, jwtToken string) tea.Cmd {
+	cmd := requestSendCommand(command, jwtToken)
+	return func() tea.Msg {
+		msg := cmd()
+		// The result must come back to this screen
+		if expired, ok := msg.(sessionExpiredMsg); ok {
+			expired.replay = func(jwtToken string) tea.Cmd {
+				return requestAccessChange(tab, command, jwtToken)
+			}
+			return expired
+		}
+		return accessChangedMsg{tab: tab, command: command, msg: msg}
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseAccessLists(t *testing.T) {
+	tests := []struct {
+		input   string
+		parse   func(string) []accessEntry
+		entries []accessEntry
+	}{
+		{
+			input:   "There are 2 whitelisted player(s): alice, bob",
+			parse:   parseWhitelist,
+			entries: []accessEntry{{name: "alice"}, {name: "bob"}},
+		},
+		{input: "There are no whitelisted players", parse: parseWhitelist},
+		{
+			input: "There are 2 ban(s):\nalice was banned by Server: Banned by an operator.\nbob was banned by admin: griefing the spawn",
+			parse: parseBanlist,
+			entries: []accessEntry{
+				{name: "alice", source: "Server", reason: "Banned by an operator."},
+				{name: "bob", source: "admin", reason: "griefing the spawn"},
+			},
+		},
+		{
+			// Without newlines
+			input: "There are 2 ban(s):1.2.3.4 was banned by Rcon: Banned by an operator.10.0.0.7 was banned by admin: spam",
+			parse: parseBanlist,
+			entries: []accessEntry{
+				{name: "1.2.3.4", source: "Rcon", reason: "Banned by an operator."},
+				{name: "10.0.0.7", source: "admin", reason: "spam"},
+			},
+		},
+		{
+			// Hex-only name and IPv6 right after the header
+			input: "There are 2 ban(s):cafe was banned by Server: Banned by an operator.::1 was banned by admin: spam",
+			parse: parseBanlist,
+			entries: []accessEntry{
+				{name: "cafe", source: "Server", reason: "Banned by an operator."},
+				{name: "::1", source: "admin", reason: "spam"},
+			},
+		},
+		{
+			input:   "There are 1 ban(s):\n2001:db8::1 was banned by Rcon: proxy",
+			parse:   parseBanlist,
+			entries: []accessEntry{{name: "2001:db8::1", source: "Rcon", reason: "proxy"}},
+		},
+		{input: "There are no bans", parse: parseBanlist},
+	}
+
+	for _, tc := range tests {
+		if entries := tc.parse(tc.input); !reflect.DeepEqual(entries, tc.entries) {
+			t.Errorf("%q: got %+v", tc.input, entries)
+		}
+	}
+}
+
+func TestAccessModel(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var model tea.Model = InitialAccessModel(InitialCommandModel(nil, "", 80, 24), "", 80, 24)
+	keys := func(keys ...tea.KeyMsg) {
+		for _, key := range keys {
+			model, _ = model.Update(key)
+		}
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	model, _ = model.Update(accessListMsg{tab: 0, entries: []accessEntry{{name: "alice"}}})
+	keys(runes("x"))
+	if confirm := model.(accessModel).confirm; confirm != "whitelist remove alice" {
+		t.Fatalf("Expected a confirmation, got %q", confirm)
+	}
+	keys(runes("n"))
+
+	// Operators are tracked by hand
+	keys(tea.KeyMsg{Type: tea.KeyTab}, runes("a"), runes("b"), runes("o"), runes("b"), tea.KeyMsg{Type: tea.KeyEnter})
+	m := model.(accessModel)
+	if m.active != 1 || m.confirm != "op bob" {
+		t.Fatalf("Expected to confirm op bob, got %d %q", m.active, m.confirm)
+	}
+	model, _ = model.Update(accessChangedMsg{tab: 1, command: "op bob", msg: commandOutputMsg{command: "op bob", output: "Made bob a server operator"}})
+	m = model.(accessModel)
+	if len(m.entries[1]) != 1 || m.entries[1][0].name != "bob" {
+		t.Errorf("Expected bob in the operators, got %+v", m.entries[1])
+	}
+	if history := m.prevModel.(commandModel).history; len(history) != 1 || history[0].command != "op bob" {
+		t.Errorf("Expected the change in the history, got %+v", history)
+	}
+}
+
+func TestAccessReauth(t *testing.T) {
+	setupFakeServer(t)
+	var model tea.Model = InitialAccessModel(InitialCommandModel(nil, expiredToken, 80, 24), expiredToken, 80, 24)
+
+	model, _ = model.Update(requestAccessList(0, expiredToken)())
+	model, _ = model.Update(requestAccessChange(0, "whitelist add bob", expiredToken)())
+	m, ok := model.(accessModel)
+	if !ok || !m.reauth.active() || len(m.reauth.pending) != 2 {
+		t.Fatalf("Expected the password prompt on the access screen, got %T", model)
+	}
+
+	model, cmd := model.Update(authMsg{username: "admin", jwtToken: testToken, sucess: true})
+	if m, ok := model.(accessModel); !ok || m.reauth.active() || m.jwtToken != testToken {
+		t.Fatalf("Expected to stay on the screen with the new token")
+	}
+	var list, changed bool
+	for _, cmd := range cmd().(tea.BatchMsg) {
+		switch msg := cmd().(type) {
+		case accessListMsg:
+			list = msg.err == nil
+		case accessChangedMsg:
+			changed = msg.command == "whitelist add bob"
+		}
+	}
+	if !list || !changed {
+		t.Errorf("Expected both requests to be replayed to the screen")
+	}
+}
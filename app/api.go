@@ -0,0 +1,102 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"mctui/cli"
+	"mctui/client"
+	"mctui/colors"
+	"mctui/config"
+	"mctui/grammar"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Shared by every screen, so all requests use the same connection settings
+var api *client.Client
+
+// Must be called before running the program
+func SetClient(c *client.Client) {
+	api = c
+}
+
+// Configures the client and theme for the server in cli.Args
+// Called on startup, or after the user picks a server
+func Connect() error {
+	if err := cli.Args.Check(); err != nil {
+		return err
+	}
+	if cli.Args.Theme != "" {
+		colors.SetTheme(cli.Args.Theme)
+	}
+	c, err := cli.Args.Client()
+	if err != nil {
+		return err
+	}
+	SetClient(c)
+
+	path, err := config.Path("commands.json")
+	if err != nil {
+		return err
+	}
+	g, err := grammar.Load(path)
+	if err != nil {
+		return err
+	}
+	commandGrammar = g
+
+	if cli.Args.Profile != "" {
+		if err := config.TouchLastUsed(cli.Args.Profile); err != nil {
+			log.Printf("Can't save last used profile: %v", err)
+		}
+	}
+	return nil
+}
+
+// Send when a request doesn't get an answer from the server
+// e.g. server down, timeout
+// Screens display it inline, so the session stays usable
+type requestErrorMsg struct {
+	// Command or task that failed
+	title string
+	err   error
+	// Makes the same request again
+	retry tea.Cmd
+	roundTrip
+}
+
+// Timing and status of a request, shown in the history gutter
+type roundTrip struct {
+	sentAt   time.Time
+	duration time.Duration
+	// 0 when the server didn't answer
+	status int
+}
+
+// Call right after the request returns
+func measure(sentAt time.Time, err error) roundTrip {
+	status := client.StatusCode(err)
+	if err == nil {
+		status = http.StatusOK
+	}
+	return roundTrip{sentAt: sentAt, duration: time.Since(sentAt), status: status}
+}
+
+// Returns true if the request didn't reach the server
+func isRequestError(err error) bool {
+	var requestErr *client.RequestError
+	return errors.As(err, &requestErr)
+}
+
+// Text displayed when a request fails
+// Prefer the server message when there is one
+func errorText(err error) string {
+	var statusErr *client.StatusError
+	if errors.As(err, &statusErr) && statusErr.Body != "" {
+		return statusErr.Body
+	}
+	return err.Error()
+}
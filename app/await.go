@@ -22,9 +22,14 @@ type modelAwait struct {
 	width       int
 	height      int
 	done        bool
-	spinner     spinner.Model
-	timer       timer.Model
-	help        help.Model
+	// Set when the server can't be reached
+	// Allows the user to try again
+	retry   tea.Cmd
+	spinner spinner.Model
+	timer   timer.Model
+	help    help.Model
+	// Goes back as soon as the task succeeds, used by macros
+	autoClose bool
 }
 
 type taskFinishedMsg struct {
@@ -32,6 +37,7 @@ type taskFinishedMsg struct {
 	msg    string
 	sucess bool
 	async  bool
+	roundTrip
 }
 
 func InitialAwaitModel(
@@ -76,6 +82,13 @@ func (m modelAwait) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyCtrlC:
 			return m, tea.Quit
 		}
+		if m.done && m.retry != nil && msg.String() == "r" {
+			log.Printf("Retry task %s", m.taskMsg.title)
+			retry := m.retry
+			m.done = false
+			m.retry = nil
+			return m, retry
+		}
 		// Forward the finish notification
 		// Parent may want to know what happens
 		if m.done {
@@ -85,10 +98,30 @@ func (m modelAwait) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 		return m, tea.ClearScreen
+	// The command screen asks the password and replays the task
+	case sessionExpiredMsg:
+		return m.prevModel.Update(msg)
+	// Output of commands sent before the task. Keep it in the history
+	case commandOutputMsg, backgroundMsg:
+		m.prevModel, cmd = m.prevModel.Update(msg)
+		return m, cmd
 	case taskFinishedMsg:
 		log.Printf("Task %s done", msg.title)
+		if m.autoClose && msg.sucess {
+			return m.prevModel, func() tea.Msg { return msg }
+		}
 		m.taskMsg = msg
 		m.done = true
+	case requestErrorMsg:
+		log.Printf("Task %s can't reach the server: %v", msg.title, msg.err)
+		m.taskMsg = taskFinishedMsg{
+			title:     msg.title,
+			msg:       fmt.Sprintf("can't reach the server: %v", msg.err),
+			sucess:    false,
+			roundTrip: msg.roundTrip,
+		}
+		m.retry = msg.retry
+		m.done = true
 	}
 
 	m.spinner, cmd = m.spinner.Update(msg)
@@ -98,6 +131,9 @@ func (m modelAwait) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m modelAwait) helpView() string {
+	if m.retry != nil {
+		return m.help.Styles.ShortKey.Render("Press r to retry or any other key to quit")
+	}
 	return m.help.Styles.ShortKey.Render("Press any key to quit")
 }
 
@@ -1,14 +1,12 @@
 package app
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 
 	"mctui/cli"
+	"mctui/client"
+	"mctui/colors"
 	"net/http"
 	"time"
 
@@ -16,6 +14,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/dustin/go-humanize"
+	"github.com/muesli/reflow/wordwrap"
 )
 
 var docStyle = lipgloss.NewStyle().Margin(1, 2)
@@ -47,11 +46,11 @@ func (b backup) timeHumanized() string {
 
 // Use the cli arg to offset the time
 func (i backup) Title() string {
-	return i.OffsetBy(time.Minute * time.Duration(cli.Args.TimeOffsetMin)).timeHumanized()
+	return i.OffsetBy(cli.Args.TimeOffset()).timeHumanized()
 }
 func (i backup) Description() string { return i.Filename }
 func (i backup) FilterValue() string {
-	return i.OffsetBy(time.Minute * time.Duration(cli.Args.TimeOffsetMin)).timeHumanized()
+	return i.OffsetBy(cli.Args.TimeOffset()).timeHumanized()
 }
 
 type backupModel struct {
@@ -60,6 +59,11 @@ type backupModel struct {
 	prevModel tea.Model
 	width     int
 	height    int
+	// Set when the backups can't be fetched
+	err   error
+	retry tea.Cmd
+	// Shown when the session expires
+	reauth screenReauth
 }
 
 func InitialBackupModel(prevModel tea.Model, jwtToken string, width, height int) backupModel {
@@ -85,8 +89,21 @@ func (m backupModel) Init() tea.Cmd {
 }
 
 func (m backupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Ask the password here, the replies come back to this screen
+	if next, cmd, ok := m.reauth.update(msg, &m.prevModel, &m.jwtToken); ok {
+		if next == nil {
+			next = m
+		}
+		return next, cmd
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Error screen only accepts retry or abort
+		if m.err != nil && msg.String() == "r" {
+			m.err = nil
+			return m, m.retry
+		}
 		switch msg.Type {
 		case tea.KeyEscape:
 			log.Printf("Escape")
@@ -104,6 +121,9 @@ func (m backupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c":
 			return m, tea.Quit
 		case "enter":
+			if m.err != nil {
+				return m, nil
+			}
 			b, ok := m.list.SelectedItem().(backup)
 			if ok {
 				backupName := b.Filename
@@ -116,12 +136,22 @@ func (m backupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
 		h, v := docStyle.GetFrameSize()
 		m.list.SetSize(msg.Width-h, msg.Height-v)
 	case fetchMsg:
 		m.list.SetItems(msg.items)
+	case requestErrorMsg:
+		m.err = msg.err
+		m.retry = msg.retry
+		return m, nil
 	case taskFinishedMsg:
 		return m.prevModel.Update(msg)
+	case backgroundMsg:
+		var cmd tea.Cmd
+		m.prevModel, cmd = m.prevModel.Update(msg)
+		return m, cmd
 	}
 
 	var cmds []tea.Cmd
@@ -133,9 +163,36 @@ func (m backupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m backupModel) View() string {
+	if view, ok := m.reauth.view(m.width, m.height); ok {
+		return view
+	}
+	if m.err != nil {
+		return m.errorView()
+	}
 	return docStyle.Render(m.list.View())
 }
 
+func (m backupModel) errorView() string {
+	centerWrapper := lipgloss.NewStyle().Align(lipgloss.Center, lipgloss.Center).Width(m.width - 2).Height(m.height - 2)
+
+	errTitle := lipgloss.NewStyle().
+		Foreground(colors.Pink).
+		Bold(true).
+		Render("Can't fetch backups")
+
+	errDescription := lipgloss.NewStyle().
+		Foreground(colors.Surface2).
+		Align(lipgloss.Center).
+		Render(wordwrap.String(fmt.Sprintf("\n%v\n", m.err), m.width-12))
+
+	helpView := lipgloss.NewStyle().
+		Foreground(colors.Surface1).
+		Render("r retry • esc go back")
+
+	both := lipgloss.JoinVertical(lipgloss.Center, errTitle, errDescription, helpView)
+	return centerWrapper.Render(both)
+}
+
 // ///////////////
 // HTTP requests
 // ///////////////
@@ -147,40 +204,35 @@ type fetchMsg struct {
 func requestMakeBackup(jwtToken string) tea.Cmd {
 	return func() tea.Msg {
 		log.Printf("Enter requestMakeBackup")
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-
-		client := &http.Client{Transport: transport}
-
-		url := fmt.Sprintf(cli.Args.Address("backup"))
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte("")))
-		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err.Error())
+		sentAt := time.Now()
+		_, err := api.Backup(jwtToken)
+		rt := measure(sentAt, err)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!backup",
+				task:  true,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestMakeBackup(jwtToken)
+				},
+			}
 		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			panic(err)
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title:     "!backup",
+				err:       err,
+				retry:     requestMakeBackup(jwtToken),
+				roundTrip: rt,
+			}
 		}
 
-		log.Printf("Return code: %d", resp.StatusCode)
-
 		var msg taskFinishedMsg
 		msg.title = "!backup"
-		msg.msg = fmt.Sprintf("%d %s", resp.StatusCode, "Backup complete")
+		msg.roundTrip = rt
+		msg.msg = fmt.Sprintf("%d %s", http.StatusOK, "Backup complete")
 		msg.sucess = true
-		if resp.StatusCode != 200 {
-			msg.msg = fmt.Sprintf("%s", body)
+		if err != nil {
+			log.Printf("Backup failed: %v", err)
+			msg.msg = errorText(err)
 			msg.sucess = false
 		}
 
@@ -190,45 +242,35 @@ func requestMakeBackup(jwtToken string) tea.Cmd {
 
 func requestRestoreBackup(backupName, jwtToken string) tea.Cmd {
 	return func() tea.Msg {
-		data := map[string]string{"filename": backupName}
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			log.Fatalf("Error marshalling JSON: %v", err)
-		}
-
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-
-		client := &http.Client{Transport: transport}
-
-		// localhost:port/restore
-		url := fmt.Sprintf(cli.Args.Address("restore"))
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err.Error())
+		sentAt := time.Now()
+		_, err := api.Restore(jwtToken, backupName)
+		rt := measure(sentAt, err)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!restore",
+				task:  true,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestRestoreBackup(backupName, jwtToken)
+				},
+			}
 		}
-		defer resp.Body.Close()
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			panic(err)
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title:     "!restore",
+				err:       err,
+				retry:     requestRestoreBackup(backupName, jwtToken),
+				roundTrip: rt,
+			}
 		}
 
 		var msg taskFinishedMsg
 		msg.title = "!restore"
-		msg.msg = fmt.Sprintf("%d %s", resp.StatusCode, "Backup restored")
+		msg.roundTrip = rt
+		msg.msg = fmt.Sprintf("%d %s", http.StatusOK, "Backup restored")
 		msg.sucess = true
-		if resp.StatusCode != 200 {
-			msg.msg = fmt.Sprintf("%s", body)
+		if err != nil {
+			log.Printf("Restore failed: %v", err)
+			msg.msg = errorText(err)
 			msg.sucess = false
 		}
 
@@ -236,41 +278,66 @@ func requestRestoreBackup(backupName, jwtToken string) tea.Cmd {
 	}
 }
 
-func fetchData(jwtToken string) tea.Cmd {
+// Restores the newest backup, used by scripts
+func requestRestoreLatest(jwtToken string) tea.Cmd {
 	return func() tea.Msg {
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		backupNames, err := api.Backups(jwtToken)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!restore",
+				task:  true,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestRestoreLatest(jwtToken)
+				},
+			}
+		}
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title: "!restore",
+				err:   err,
+				retry: requestRestoreLatest(jwtToken),
+			}
 		}
-
-		client := &http.Client{Transport: transport}
-
-		url := fmt.Sprintf(cli.Args.Address("backups"))
-		req, err := http.NewRequest("GET", url, bytes.NewBuffer([]byte("")))
 		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
+			return taskFinishedMsg{title: "!restore", msg: errorText(err)}
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err.Error())
+		var latest *backup
+		for _, name := range backupNames {
+			b, err := NewBackup(name)
+			if err == nil && (latest == nil || b.Time.After(latest.Time)) {
+				latest = b
+			}
 		}
-		defer resp.Body.Close()
+		if latest == nil {
+			return taskFinishedMsg{title: "!restore", msg: "no backups to restore"}
+		}
+		log.Printf("Latest backup is %s", latest.Filename)
+		return requestRestoreBackup(latest.Filename, jwtToken)()
+	}
+}
 
-		if resp.StatusCode != 200 {
+func fetchData(jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		backupNames, err := api.Backups(jwtToken)
+		if client.IsUnauthorized(err) {
 			log.Printf("session expired: login again")
-			return sessionExpiredMsg("session expired: login again")
+			return sessionExpiredMsg{
+				title: "!restore",
+				replay: func(jwtToken string) tea.Cmd {
+					return fetchData(jwtToken)
+				},
+			}
 		}
-		body, err := io.ReadAll(resp.Body)
-
-		var backupNames []string
-		if err := json.Unmarshal(body, &backupNames); err != nil {
-			panic(err)
+		if err != nil {
+			log.Printf("Can't fetch backups: %v", err)
+			return requestErrorMsg{
+				title: "!restore",
+				err:   err,
+				retry: fetchData(jwtToken),
+			}
 		}
 
-		var backups []backup
 		var items []list.Item
 		for _, name := range backupNames {
 			b, err := NewBackup(name)
@@ -278,7 +345,6 @@ func fetchData(jwtToken string) tea.Cmd {
 				log.Printf("Skip backup with bad name: %v", err)
 				continue
 			}
-			backups = append(backups, *b)
 			items = append(items, *b)
 		}
 
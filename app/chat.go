@@ -0,0 +1,153 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"mctui/cli"
+	"mctui/colors"
+	"mctui/console"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// How the chat tab sends what is typed
+type chatMode struct {
+	// tellraw @a instead of say
+	tellraw bool
+	// Player for tell, empty to talk to everyone
+	whisper string
+}
+
+// Command that sends text to the chat
+func (c chatMode) command(text string) string {
+	switch {
+	case c.whisper != "":
+		return fmt.Sprintf("tell %s %s", c.whisper, text)
+	case c.tellraw:
+		// say shows [Rcon], tellraw shows who we are
+		components := []any{
+			"",
+			map[string]string{"text": fmt.Sprintf("<%s> ", chatName()), "color": "light_purple"},
+			map[string]string{"text": text},
+		}
+		// Keep < and > readable in the history
+		var data strings.Builder
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		encoder.Encode(components)
+		return "tellraw @a " + strings.TrimSpace(data.String())
+	default:
+		return "say " + text
+	}
+}
+
+func (c chatMode) label() string {
+	switch {
+	case c.whisper != "":
+		return "tell " + c.whisper
+	case c.tellraw:
+		return "tellraw"
+	default:
+		return "say"
+	}
+}
+
+func chatName() string {
+	if cli.Args.Username != "" {
+		return cli.Args.Username
+	}
+	return "mctui"
+}
+
+func (m commandModel) sendChat(text string) (tea.Model, tea.Cmd) {
+	m.commandInput.SetValue("")
+	return m, requestSendCommand(m.chat.command(text), m.jwtToken)
+}
+
+// Keys of the chat tab
+func (m commandModel) updateChatKeys(msg tea.KeyMsg) (commandModel, bool) {
+	switch msg.String() {
+	case "alt+t":
+		m.chat.tellraw = !m.chat.tellraw
+	case "alt+w":
+		if m.chat.whisper != "" {
+			m.chat.whisper = ""
+		} else {
+			m.chat.whisper = m.whisperTarget()
+		}
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+// The player selected in the panel, or the last one who talked
+func (m commandModel) whisperTarget() string {
+	if p := m.players; p != nil && len(p.names) > 0 {
+		return p.names[p.selected]
+	}
+	if m.console == nil {
+		return ""
+	}
+	for i := len(m.console.lines) - 1; i >= 0; i-- {
+		c, ok := console.ParseChat(m.console.lines[i])
+		if ok && (c.Kind == console.Message || c.Kind == console.Action) {
+			return c.Player
+		}
+	}
+	return ""
+}
+
+func (m commandModel) updateChatContent() commandModel {
+	width := m.chatView.Width
+	var lines []string
+	for _, line := range m.console.lines {
+		if c, ok := console.ParseChat(line); ok {
+			lines = append(lines, lipgloss.NewStyle().Width(width).Render(chatLineView(c)))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, lipgloss.NewStyle().Foreground(colors.Surface2).Render("No chat yet"))
+	}
+	// Stay where the user scrolled to
+	atBottom := m.chatView.AtBottom()
+	m.chatView.SetContent(strings.Join(lines, "\n"))
+	if atBottom {
+		m.chatView.GotoBottom()
+	}
+	return m
+}
+
+// e.g. 12:34:56 <alice> hello
+func chatLineView(c console.Chat) string {
+	dim := lipgloss.NewStyle().Foreground(colors.Surface2)
+	text := lipgloss.NewStyle().Foreground(colors.Text)
+	name := lipgloss.NewStyle().Foreground(nameColor(c.Player)).Bold(true).Render(c.Player)
+
+	var line string
+	switch c.Kind {
+	case console.Message:
+		line = fmt.Sprintf("<%s> %s", name, text.Render(c.Text))
+	case console.Broadcast:
+		name = lipgloss.NewStyle().Foreground(colors.Pink).Bold(true).Render(c.Player)
+		line = fmt.Sprintf("[%s] %s", name, text.Render(c.Text))
+	case console.Action:
+		line = fmt.Sprintf("* %s %s", name, text.Italic(true).Render(c.Text))
+	case console.Joined:
+		line = lipgloss.NewStyle().Foreground(colors.Green).Render("→ ") + name + dim.Render(" joined")
+	case console.Left:
+		line = lipgloss.NewStyle().Foreground(colors.Red).Render("← ") + name + dim.Render(" left")
+	}
+	return dim.Render(c.Time) + " " + line
+}
+
+// Each player keeps the same color
+func nameColor(player string) lipgloss.Color {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(player)))
+	return colors.Accents[h.Sum32()%uint32(len(colors.Accents))]
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+
+	"mctui/cli"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestChatCommand(t *testing.T) {
+	previous := cli.Args
+	t.Cleanup(func() { cli.Args = previous })
+	cli.Args.Username = "mod"
+
+	tests := []struct {
+		mode    chatMode
+		command string
+	}{
+		{mode: chatMode{}, command: "say hello"},
+		{mode: chatMode{tellraw: true}, command: `tellraw @a ["",{"color":"light_purple","text":"<mod> "},{"text":"hello"}]`},
+		{mode: chatMode{tellraw: true, whisper: "alice"}, command: "tell alice hello"},
+	}
+	for _, tc := range tests {
+		if command := tc.mode.command("hello"); command != tc.command {
+			t.Errorf("%+v: got %s", tc.mode, command)
+		}
+	}
+}
+
+func TestChatTab(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 80, 24)
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m, _ = model.(commandModel).showTab(chatTab)
+	m.console.add([]string{
+		"[12:00:00] [Server thread/INFO]: alice joined the game",
+		"[12:00:01] [Server thread/INFO]: <alice> anyone there?",
+		"[12:00:02] [Server thread/INFO]: Saved the game",
+	})
+	m = m.updateConsoleContent()
+	if lines := m.chatView.TotalLineCount(); lines != 2 {
+		t.Errorf("Expected 2 chat lines, got %d", lines)
+	}
+
+	// Whispers to the last one who talked
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("w"), Alt: true})
+	m = model.(commandModel)
+	if m.chat.whisper != "alice" {
+		t.Fatalf("Expected to whisper to alice, got %q", m.chat.whisper)
+	}
+
+	m.commandInput.SetValue("hi")
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(commandModel)
+	if cmd == nil || m.commandInput.Value() != "" || len(m.commands) != 0 {
+		t.Errorf("Expected the message to be sent and not remembered")
+	}
+}
@@ -1,16 +1,16 @@
 package app
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"mctui/cli"
+	"mctui/client"
 	"mctui/colors"
+	"mctui/history"
+	"mctui/mcformat"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
@@ -21,12 +21,21 @@ import (
 
 var useHighPerformanceRenderer = false
 
+const commandPlaceholder = "e.g. kill player1"
+
 // Main screen
 // Displays a history and a command prompt
 type commandModel struct {
 	history []commandOutputMsg
+	// Commands typed, oldest first. Kept between sessions
+	commands    []string
+	historyFile *history.File
 	// Used to fill the input wuen user press Up/Down arrows
 	historyIndex int
+	// Shown with ctrl+r
+	search *reverseSearch
+	// Search in the output, shown with ctrl+f
+	find         *viewportSearch
 	commandInput textinput.Model
 	viewport     viewport.Model
 	prevModel    tea.Model
@@ -35,6 +44,31 @@ type commandModel struct {
 	width        int
 	height       int
 	err          error
+	// Shown when the session expires
+	reauth *reauthPrompt
+	// Requests rejected by the server, replayed after login
+	pending   []sessionExpiredMsg
+	completer completer
+	// Invalid command sent anyway if enter is pressed again
+	rejected string
+	// Shows the output without § codes and JSON colors
+	plainOutput bool
+	// Shows times and status next to the commands
+	showGutter bool
+	// Set while a macro or script runs
+	run *stepRun
+	// Jobs of !schedule
+	schedules *scheduler
+	// Shown with ctrl+p
+	players *playerPanel
+	// Polled once the dashboard is opened
+	perf *perfMonitor
+	// History, console or chat, switched with ctrl+n
+	tab         int
+	console     *consoleStream
+	consoleView viewport.Model
+	chatView    viewport.Model
+	chat        chatMode
 }
 
 // Send after rcon commands, tasks
@@ -43,26 +77,37 @@ type commandModel struct {
 type commandOutputMsg struct {
 	command string
 	output  string
+	failed  bool
+	task    bool
+	// Set when added to the history
+	receivedAt time.Time
+	// Empty for client side commands
+	roundTrip
 }
 
-type sessionExpiredMsg string
-
 func InitialCommandModel(prevModel tea.Model, jwtToken string, width, height int) commandModel {
 	ci := textinput.New()
-	ci.Placeholder = "e.g. kill player1"
+	ci.Placeholder = commandPlaceholder
 	ci.Focus()
 	ci.CharLimit = 128
 	ci.Prompt = "> "
 	ci.PlaceholderStyle = lipgloss.NewStyle().Foreground(colors.Surface1)
 	ci.PromptStyle = lipgloss.NewStyle().Foreground(colors.Pink)
 
+	historyFile, commands := openHistory()
+
 	return commandModel{
+		commands:     commands,
+		historyFile:  historyFile,
 		commandInput: ci,
 		err:          nil,
 		jwtToken:     jwtToken,
 		width:        width,
 		height:       height,
 		prevModel:    prevModel,
+		// Init fetches the data
+		completer: completer{fetching: true},
+		schedules: &scheduler{},
 	}
 }
 
@@ -74,27 +119,62 @@ func (m commandModel) Init() tea.Cmd {
 		func() tea.Msg {
 			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
 		},
+		requestCompletionData(m.jwtToken),
 	)
 }
 
-func (e *commandOutputMsg) View(windowWidth int) string {
+// Output colors come from the server unless plain is set
+// The gutter shows when the command was sent and how it went
+func (e *commandOutputMsg) View(windowWidth int, plain, gutter bool) string {
 	commandStyle := lipgloss.NewStyle().
 		Foreground(colors.Pink).
 		Bold(true)
 	commandStr := commandStyle.Render(e.command)
+	if gutter {
+		gutterStr := lipgloss.NewStyle().Foreground(colors.Surface1).Render(e.gutter())
+		space := windowWidth - lipgloss.Width(commandStr) - lipgloss.Width(gutterStr)
+		if space > 0 {
+			commandStr += strings.Repeat(" ", space) + gutterStr
+		}
+	}
 
 	outputStyle := lipgloss.NewStyle().
 		Foreground(colors.Surface2)
 		// Background(colors.Surface0)
+	if e.failed {
+		outputStyle = outputStyle.Foreground(colors.Red)
+	}
 
-		// Wrap on newline characters
-	wrapped := wrapCommandOutput(e.output, windowWidth)
-	outputStr := outputStyle.Render(wrapped)
+	// Failed requests show our own errors, not server output
+	var outputStr string
+	if plain || e.failed {
+		wrapped := wrapCommandOutput(mcformat.Strip(e.output), windowWidth)
+		outputStr = outputStyle.Render(wrapped)
+	} else {
+		// Wraps after rendering, the wrapper skips escape sequences
+		outputStr = wrapCommandOutput(mcformat.Render(e.output, outputStyle), windowWidth)
+	}
 
 	both := fmt.Sprintf("%s\n%s\n", commandStr, outputStr)
 	return both
 }
 
+// e.g. 14:03:21 • 35ms • 200 • task
+func (e *commandOutputMsg) gutter() string {
+	if e.sentAt.IsZero() {
+		return e.receivedAt.Format("15:04:05")
+	}
+	status := "no answer"
+	if e.status != 0 {
+		status = strconv.Itoa(e.status)
+	}
+	parts := []string{e.sentAt.Format("15:04:05"), e.duration.Round(time.Millisecond).String(), status}
+	if e.task {
+		parts = append(parts, "task")
+	}
+	return strings.Join(parts, " • ")
+}
+
 func wrapCommandOutput(output string, windowWidth int) string {
 	// This library has a weird behaviour when the last
 	// world just disapears when there is no wrap
@@ -109,17 +189,6 @@ func wrapCommandOutput(output string, windowWidth int) string {
 	w.Write([]byte(output))
 
 	return w.String()
-
-	// if _, err := w.Write([]byte(output)); err != nil {
-	// 	log.Fatalf("can't wrap text: %v", err)
-	// }
-	// s := w.String()
-	//    if len(s) == 0 {
-	//        return output
-	//    }
-	//    return s
-
-	return wordwrap.String(output, windowWidth)
 }
 
 func isTask(command string) bool {
@@ -141,10 +210,150 @@ func (m commandModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.reauth != nil {
+			switch msg.Type {
+			case tea.KeyCtrlC:
+				return m, tea.Quit
+			case tea.KeyEscape:
+				m.reauth = nil
+				m.pending = nil
+				return m.backToLogin()
+			}
+			return m, m.reauth.Update(msg)
+		}
+
+		if m.run != nil && m.run.confirm != "" {
+			return m.updateConfirm(msg)
+		}
+
+		if m.find != nil {
+			var handled bool
+			if m, cmd, handled = m.updateFind(msg); handled {
+				return m, cmd
+			}
+		}
+
+		if m.search != nil {
+			var handled bool
+			if m, handled = m.updateSearch(msg); handled {
+				return m, nil
+			}
+		}
+
+		if m.players != nil && m.players.focused && msg.Type != tea.KeyCtrlP {
+			var handled bool
+			if m, handled = m.updatePlayers(msg); handled {
+				return m, nil
+			}
+		}
+
+		if m.tab == consoleTab && m.console != nil {
+			var handled bool
+			if m, handled = m.updateConsoleKeys(msg); handled {
+				return m, nil
+			}
+		}
+		if m.tab == chatTab {
+			var handled bool
+			if m, handled = m.updateChatKeys(msg); handled {
+				return m, nil
+			}
+		}
+		switch msg.String() {
+		case "alt+1":
+			m, cmd = m.showTab(historyTab)
+			return m, cmd
+		case "alt+2":
+			m, cmd = m.showTab(consoleTab)
+			return m, cmd
+		case "alt+3":
+			m, cmd = m.showTab(chatTab)
+			return m, cmd
+		}
+
+		switch msg.Type {
+		case tea.KeyTab, tea.KeyShiftTab:
+			completed := m.completer.next(m.commandInput.Value(), msg.Type == tea.KeyShiftTab)
+			m.commandInput.SetValue(completed)
+			m.commandInput.CursorEnd()
+			if m.completer.stale() {
+				m.completer.fetching = true
+				return m, requestCompletionData(m.jwtToken)
+			}
+			return m, nil
+		case tea.KeyEscape:
+			if m.completer.active() {
+				m.commandInput.SetValue(m.completer.cancel())
+				m.commandInput.CursorEnd()
+				return m, nil
+			}
+			if m.run != nil {
+				m = m.stopRun("cancelled")
+				return m, nil
+			}
+		default:
+			// Any other key accepts the candidate
+			m.completer.close()
+		}
+
 		switch msg.Type {
 		case tea.KeyCtrlC:
 			return m, tea.Quit
 
+		case tea.KeyCtrlR:
+			m.search = newReverseSearch(m.commandInput.Value())
+			return m, nil
+
+		case tea.KeyCtrlF:
+			m.find = newViewportSearch()
+			return m, textinput.Blink
+
+			// The viewport keymap is cleared, arrows belong to the prompt
+		case tea.KeyPgUp:
+			switch m.tab {
+			case consoleTab:
+				// Stop following to read older lines
+				m.consoleView.HalfViewUp()
+				m.console.follow = false
+			case chatTab:
+				m.chatView.HalfViewUp()
+			default:
+				m.viewport.HalfViewUp()
+			}
+			return m, nil
+		case tea.KeyPgDown:
+			switch m.tab {
+			case consoleTab:
+				m.consoleView.HalfViewDown()
+				m.console.follow = m.consoleView.AtBottom()
+			case chatTab:
+				m.chatView.HalfViewDown()
+			default:
+				m.viewport.HalfViewDown()
+			}
+			return m, nil
+
+		case tea.KeyCtrlN:
+			m, cmd = m.showTab((m.tab + 1) % len(tabNames))
+			return m, cmd
+
+		case tea.KeyCtrlO:
+			return m.export(""), nil
+
+		case tea.KeyCtrlP:
+			m, cmd = m.togglePlayers()
+			return m, cmd
+
+		case tea.KeyCtrlG:
+			m.showGutter = !m.showGutter
+			m = m.updateViewportContent()
+			return m, nil
+
+		case tea.KeyCtrlT:
+			m.plainOutput = !m.plainOutput
+			m = m.updateViewportContent()
+			return m, nil
+
 			// Clear the screen
 		case tea.KeyCtrlL:
 			m.history = nil
@@ -153,105 +362,371 @@ func (m commandModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			// Fill the input with previous commands
 		case tea.KeyUp:
-			m.historyIndex = clamp(m.historyIndex+1, 0, len(m.history))
+			m.historyIndex = clamp(m.historyIndex+1, 0, len(m.commands))
 			if m.historyIndex > 0 {
-				m.commandInput.SetValue(m.history[len(m.history)-m.historyIndex].command)
-				if m.commandInput.Value() == "<empty>" {
-					m.commandInput.SetValue("")
-				}
-				m.commandInput.SetCursor(m.commandInput.CharLimit)
+				m.commandInput.SetValue(m.commands[len(m.commands)-m.historyIndex])
+				m.commandInput.CursorEnd()
 			}
 		case tea.KeyDown:
-			m.historyIndex = clamp(m.historyIndex-1, 0, len(m.history))
+			m.historyIndex = clamp(m.historyIndex-1, 0, len(m.commands))
 			if m.historyIndex > 0 {
-				m.commandInput.SetValue(m.history[len(m.history)-m.historyIndex].command)
-				if m.commandInput.Value() == "<empty>" {
-					m.commandInput.SetValue("")
-				}
-			} else if m.historyIndex == 0 {
+				m.commandInput.SetValue(m.commands[len(m.commands)-m.historyIndex])
+			} else {
 				m.commandInput.SetValue("")
-				m.commandInput.SetCursor(m.commandInput.CharLimit)
 			}
+			m.commandInput.CursorEnd()
 
 		case tea.KeyEnter:
 			log.Printf("User input: %s", m.commandInput.Value())
 			userCmd := m.commandInput.Value()
 			m.historyIndex = 0
 
+			// Chat tab talks to the players, / sends a command
+			if m.tab == chatTab && userCmd != "" && !strings.HasPrefix(userCmd, "/") && !isTask(userCmd) {
+				return m.sendChat(userCmd)
+			}
+
+			// Client side commands. The server never sees them
+			if userCmd == "!logout" {
+				m.commandInput.SetValue("")
+				forgetToken(m.jwtToken)
+				return m.backToLogin()
+			}
+
+			if userCmd == "!export" || strings.HasPrefix(userCmd, "!export ") {
+				m = m.remember(userCmd)
+				m.commandInput.SetValue("")
+				path := strings.TrimSpace(strings.TrimPrefix(userCmd, "!export"))
+				return m.export(path), nil
+			}
+
+			if fields := strings.Fields(userCmd); len(fields) > 0 && fields[0] == "!run" {
+				m = m.remember(userCmd)
+				m.commandInput.SetValue("")
+				runner, err := loadScript(fields[1:])
+				if err != nil {
+					m = m.appendHistory(commandOutputMsg{command: userCmd, output: err.Error(), failed: true})
+					return m, nil
+				}
+				return m.startRun(runner)
+			}
+
+			if userCmd == "!schedule" || strings.HasPrefix(userCmd, "!schedule ") {
+				return m.addSchedule(userCmd)
+			}
+
+			if userCmd == "!dashboard" {
+				m = m.remember(userCmd)
+				m.commandInput.SetValue("")
+				return m.openDashboard()
+			}
+
+			if userCmd == "!players" {
+				m = m.remember(userCmd)
+				m.commandInput.SetValue("")
+				newModel := InitialAccessModel(m, m.jwtToken, m.width, m.height)
+				return newModel, newModel.Init()
+			}
+
 			// Quick hack. Windows doesn't like f1 shortcut
 			if userCmd == "!restore" {
+				m = m.remember(userCmd)
 				m.commandInput.SetValue("")
 				newModel := InitialBackupModel(m, m.jwtToken, m.width, m.height)
 				return newModel, newModel.Init()
 			}
 
+			// Aliases and macros from the config file
+			typed := userCmd
+			steps, ok, err := userMacros.Expand(userCmd)
+			if err != nil {
+				m.commandInput.SetValue("")
+				m = m.appendHistory(commandOutputMsg{command: userCmd, output: err.Error(), failed: true})
+				return m, nil
+			}
+			if ok && (len(steps) != 1 || steps[0].IsWait()) {
+				m = m.remember(typed)
+				m.commandInput.SetValue("")
+				return m.startRun(&macroSteps{name: strings.Fields(typed)[0], steps: steps})
+			}
+			if ok {
+				userCmd = steps[0].Command
+			}
+
+			// The grammar may not know plugins or newer versions
+			if err := commandGrammar.Check(userCmd); err != nil && m.rejected != typed {
+				log.Printf("Invalid command %s: %v", userCmd, err)
+				m.rejected = typed
+				return m, nil
+			}
+			m.rejected = ""
+			m = m.remember(typed)
+
 			m.commandInput.SetValue("")
 			taskCmd := parseCommand(m, userCmd, m.jwtToken)
 
 			// Tasks may take some time
 			// Change to the awaitModel
 			if isTask(userCmd) {
-				msgLoading := fmt.Sprintf("Waiting for task %s", userCmd)
-				msgDone := fmt.Sprintf("Task %s done!", userCmd)
-				awaitModel := InitialAwaitModel(m, taskCmd, m.width, m.height, msgLoading, msgDone)
-				cmd := awaitModel.Init()
-				return awaitModel, cmd
+				return m.runTask(userCmd, taskCmd)
 			}
 			return m, taskCmd
 
 		case tea.KeyF1:
 			newModel := InitialBackupModel(m, m.jwtToken, m.width, m.height)
 			return newModel, newModel.Init()
+
+		case tea.KeyF2:
+			newModel := InitialAccessModel(m, m.jwtToken, m.width, m.height)
+			return newModel, newModel.Init()
+
+		case tea.KeyF3:
+			return m.openDashboard()
 		}
 
-	case commandOutputMsg:
-		m.history = append(m.history, msg)
-		m = m.updateViewportContent()
+	// Tasks get forwarded from awaitModel
+	case commandOutputMsg, taskFinishedMsg, requestErrorMsg:
+		entry, _ := historyEntry(msg)
+		m = m.appendHistory(entry)
+		return m.stepDone(entry)
 
-	// We get the message forwarded from awaitModel
-	case taskFinishedMsg:
-		m.history = append(m.history, commandOutputMsg{
-			command: msg.title,
-			output:  msg.msg,
+	case scheduleTickMsg:
+		if m.schedules == nil {
+			return m, nil
+		}
+		return m.runDueJobs(time.Now())
+
+	case scheduledResultMsg:
+		return m.scheduledResult(msg)
+
+	case logConnectedMsg, logLinesMsg, logClosedMsg, logReconnectMsg:
+		return m.updateConsole(msg)
+
+	case perfPollMsg, perfSampleMsg:
+		return m.updatePerf(msg)
+
+	// Polls stop when the panel is hidden
+	case playerPollMsg:
+		if m.players != msg.panel {
+			return m, nil
+		}
+		return m, requestPlayers(msg.panel, m.jwtToken)
+
+	case playerListMsg:
+		if m.players != msg.panel {
+			return m, nil
+		}
+		m.players.update(msg.msg, time.Now())
+		if m.players.err == "" {
+			m.completer.players = m.players.names
+		}
+		// The polls go on with the new token
+		if _, ok := msg.msg.(sessionExpiredMsg); ok {
+			panel := msg.panel
+			return m, expiredCmd(sessionExpiredMsg{
+				title: "list",
+				replay: func(jwtToken string) tea.Cmd {
+					return requestPlayers(panel, jwtToken)
+				},
+			})
+		}
+		return m, tea.Tick(playersInterval(), func(time.Time) tea.Msg {
+			return playerPollMsg{panel: msg.panel}
 		})
-		log.Printf("Append task %s to history", msg.title)
-		m = m.updateViewportContent()
 
-	// Go back to login screen
+	case runSleepMsg:
+		// The run may have been cancelled meanwhile
+		if m.run == msg.run {
+			return m.nextStep()
+		}
+		return m, nil
+
+	// Ask the password again, then replay the request
 	case sessionExpiredMsg:
-		return m.prevModel.Update(nil)
+		log.Printf("Session expired running %s", msg.title)
+		if m.reauth == nil {
+			m.reauth = newReauthPrompt(storedUsername(m.jwtToken))
+			forgetToken(m.jwtToken)
+		}
+		m.pending = append(m.pending, msg)
+		return m, nil
+
+	case authMsg:
+		if m.reauth == nil {
+			break
+		}
+		if !msg.sucess {
+			err := msg.err
+			if err == nil {
+				err = fmt.Errorf("bad credentials")
+			}
+			m.reauth.Failed(err)
+			return m, nil
+		}
+		m.reauth = nil
+		m.jwtToken = msg.jwtToken
+		saveToken(msg.username, msg.jwtToken)
+		return m.replayPending()
+
+	case completionDataMsg:
+		if len(msg.commands) > 0 {
+			m.completer.commands = msg.commands
+		}
+		m.completer.players = msg.players
+		m.completer.fetchedAt = time.Now()
+		m.completer.fetching = false
+		return m, nil
+
+	// Another screen logged in again
+	case tokenRefreshedMsg:
+		m.jwtToken = msg.jwtToken
+		return m, nil
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		m.commandInput.Width = m.width
-		marginVertical := lipgloss.Height(m.promptView())
-
-		if !m.ready {
-			m.viewport = viewport.New(msg.Width, msg.Height-marginVertical)
-			// Remove default keymaps
-			m.viewport.KeyMap = viewport.KeyMap{}
-			m.viewport.MouseWheelEnabled = true
-			m.viewport.YPosition = 0
-			m.ready = true
-		} else {
-			m.viewport.Width = msg.Width
-			m.viewport.Height = msg.Height - marginVertical
-		}
-		m = m.updateViewportContent()
+		m = m.resize()
 	}
 
 	m.commandInput, cmd = m.commandInput.Update(msg)
 	cmds = append(cmds, cmd)
-	m.viewport, cmd = m.viewport.Update(msg)
+	// Cursor blink of the search input
+	if m.find != nil {
+		m.find.input, cmd = m.find.input.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	// Mouse wheel scrolls the tab shown
+	switch m.tab {
+	case consoleTab:
+		m.consoleView, cmd = m.consoleView.Update(msg)
+	case chatTab:
+		m.chatView, cmd = m.chatView.Update(msg)
+	default:
+		m.viewport, cmd = m.viewport.Update(msg)
+	}
 	cmds = append(cmds, cmd)
 
 	return m, tea.Batch(cmds...)
 }
 
+// Turns the result of a request into a history entry
+func historyEntry(msg tea.Msg) (commandOutputMsg, bool) {
+	switch msg := msg.(type) {
+	case commandOutputMsg:
+		return msg, true
+	case taskFinishedMsg:
+		return commandOutputMsg{
+			command:   msg.title,
+			output:    msg.msg,
+			failed:    !msg.sucess,
+			task:      true,
+			roundTrip: msg.roundTrip,
+		}, true
+	// Server is unreachable. Keep the session, the user can try again later
+	case requestErrorMsg:
+		return commandOutputMsg{
+			command:   msg.title,
+			output:    fmt.Sprintf("can't reach the server: %v", msg.err),
+			failed:    true,
+			task:      isTask(msg.title),
+			roundTrip: msg.roundTrip,
+		}, true
+	}
+	return commandOutputMsg{}, false
+}
+
+// Fits the viewport in the window, next to the player panel
+func (m commandModel) resize() commandModel {
+	m.commandInput.Width = m.width
+	marginVertical := lipgloss.Height(m.promptView())
+	// Tabs are shown once the console is open
+	if m.console != nil {
+		marginVertical += lipgloss.Height(m.tabsView())
+	}
+
+	if !m.ready {
+		m.viewport = viewport.New(m.historyWidth(), m.height-marginVertical)
+		// Remove default keymaps
+		m.viewport.KeyMap = viewport.KeyMap{}
+		m.viewport.MouseWheelEnabled = true
+		m.viewport.YPosition = 0
+		m.ready = true
+	} else {
+		m.viewport.Width = m.historyWidth()
+		m.viewport.Height = m.height - marginVertical
+	}
+	m.consoleView.Width = m.viewport.Width
+	m.consoleView.Height = m.viewport.Height
+	m.chatView.Width = m.viewport.Width
+	m.chatView.Height = m.viewport.Height
+	m = m.updateConsoleContent()
+	return m.updateViewportContent()
+}
+
+func (m commandModel) historyWidth() int {
+	if m.players != nil {
+		return max(m.width-playersWidth, 0)
+	}
+	return m.width
+}
+
+// Tasks may take some time, so they run in the await screen
+func (m commandModel) runTask(title string, taskCmd tea.Cmd) (tea.Model, tea.Cmd) {
+	msgLoading := fmt.Sprintf("Waiting for task %s", title)
+	msgDone := fmt.Sprintf("Task %s done!", title)
+	awaitModel := InitialAwaitModel(m, taskCmd, m.width, m.height, msgLoading, msgDone)
+	return awaitModel, awaitModel.Init()
+}
+
+// Sends the requests rejected before the login again, using the new token
+func (m commandModel) replayPending() (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	var task *sessionExpiredMsg
+	for _, p := range m.pending {
+		if p.replay == nil {
+			continue
+		}
+		log.Printf("Replay %s", p.title)
+		if p.task && task == nil {
+			task = &p
+			continue
+		}
+		cmds = append(cmds, p.replay(m.jwtToken))
+	}
+	m.pending = nil
+
+	if task != nil {
+		newModel, cmd := m.runTask(task.title, task.replay(m.jwtToken))
+		return newModel, tea.Batch(append(cmds, cmd)...)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// The login screen may have never received the window size,
+// e.g. when we start with a stored session
+func (m commandModel) backToLogin() (tea.Model, tea.Cmd) {
+	if m.console != nil {
+		m.console.stop()
+	}
+	return m.prevModel.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+}
+
+func (m commandModel) appendHistory(entry commandOutputMsg) commandModel {
+	if entry.receivedAt.IsZero() {
+		entry.receivedAt = time.Now()
+	}
+	m.history = append(m.history, entry)
+	return m.updateViewportContent()
+}
+
 func (m commandModel) updateViewportContent() commandModel {
-	m.viewport.SetContent(m.HistoryView())
-	if m.viewport.TotalLineCount() > m.height {
+	content := m.HistoryView()
+	if m.find != nil {
+		content = m.find.highlight(content)
+	}
+	m.viewport.SetContent(content)
+	// Don't move away from the matches
+	if m.find == nil && m.viewport.TotalLineCount() > m.height {
 		m.viewport.GotoBottom()
 	}
 	return m
@@ -260,7 +735,7 @@ func (m commandModel) updateViewportContent() commandModel {
 func (m commandModel) HistoryView() string {
 	var lines strings.Builder
 	for _, command := range m.history {
-		line := command.View(m.width)
+		line := command.View(m.historyWidth(), m.plainOutput, m.showGutter)
 		lines.WriteString(line)
 		lines.WriteString("\n")
 	}
@@ -271,15 +746,79 @@ func (m commandModel) HistoryView() string {
 
 func (m commandModel) promptView() string {
 	labelStye := lipgloss.NewStyle().Foreground(colors.Pink)
-	commandLabel := labelStye.Render(fmt.Sprintf("%s", "command"))
+	label := "command"
+	if m.tab == chatTab {
+		label = m.chat.label()
+	}
+	commandLabel := labelStye.Render(label)
 	commandView := fmt.Sprintf("%s%s", commandLabel, m.commandInput.View())
-	commandView = lipgloss.NewStyle().Margin(1, 0, 0, 0).Render(commandView)
-	return commandView
+
+	// The completion and hints use the blank line above the prompt
+	popup := m.hintView()
+	switch {
+	case m.run != nil && m.run.confirm != "":
+		popup = m.confirmView()
+	case m.players != nil && m.players.focused:
+		popup = m.playersHintView()
+	case m.find != nil:
+		popup = m.findView()
+	case m.search != nil:
+		popup = m.searchView()
+	case m.completer.active():
+		popup = m.completer.View(m.width)
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, popup, commandView)
+}
+
+// Signature of the command being typed, or what is wrong with it
+func (m commandModel) hintView() string {
+	input := m.commandInput.Value()
+	style := lipgloss.NewStyle().MaxWidth(m.width).MaxHeight(1)
+
+	// Chat messages are not commands
+	if m.tab == chatTab && !strings.HasPrefix(input, "/") {
+		return style.Foreground(colors.Surface1).Render("alt+t say/tellraw • alt+w whisper • start with / to send a command")
+	}
+
+	if input != "" && input == m.rejected {
+		err := commandGrammar.Check(expandAlias(input))
+		return style.Foreground(colors.Red).Render(fmt.Sprintf("%v • enter to send anyway", err))
+	}
+
+	usages, err := commandGrammar.Hints(input)
+	if err != nil {
+		return style.Foreground(colors.Red).Render(err.Error())
+	}
+	if len(usages) == 0 {
+		return ""
+	}
+	name := strings.TrimPrefix(strings.Fields(input)[0], "/")
+	var hints []string
+	for _, usage := range usages {
+		hints = append(hints, strings.TrimSpace(name+" "+usage.String()))
+	}
+	return style.Foreground(colors.Surface2).Render(strings.Join(hints, "  │  "))
 }
 
 func (m commandModel) View() string {
+	historyView := m.viewport.View()
+	switch m.tab {
+	case consoleTab:
+		historyView = m.consoleView.View()
+	case chatTab:
+		historyView = m.chatView.View()
+	}
+	if m.reauth != nil {
+		historyView = m.reauth.View(m.viewport.Width, m.viewport.Height)
+	}
+	if m.players != nil {
+		historyView = lipgloss.JoinHorizontal(lipgloss.Top, historyView, m.playersView())
+	}
+	if m.console != nil {
+		historyView = lipgloss.JoinVertical(lipgloss.Left, m.tabsView(), historyView)
+	}
 	both := lipgloss.JoinVertical(lipgloss.Left,
-		m.viewport.View(),
+		historyView,
 		m.promptView())
 	return fmt.Sprintf("%s", both)
 }
@@ -287,6 +826,14 @@ func (m commandModel) View() string {
 func parseCommand(m tea.Model, command string, jwtToken string) tea.Cmd {
 	if strings.HasPrefix(command, "!") {
 		withoutPrefix := command[1:]
+		// Scripts restore without the backup screen
+		if name, ok := strings.CutPrefix(command, "!restore "); ok {
+			name = strings.TrimSpace(name)
+			if name == "latest" {
+				return withTitle(command, requestRestoreLatest(jwtToken))
+			}
+			return withTitle(command, requestRestoreBackup(name, jwtToken))
+		}
 		switch command {
 		case "!backup":
 			return requestMakeBackup(jwtToken)
@@ -299,53 +846,63 @@ func parseCommand(m tea.Model, command string, jwtToken string) tea.Cmd {
 	return requestSendCommand(command, jwtToken)
 }
 
+// Names the result of cmd with title, so it matches what was typed
+func withTitle(title string, cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		switch msg := cmd().(type) {
+		case taskFinishedMsg:
+			msg.title = title
+			return msg
+		case requestErrorMsg:
+			msg.title = title
+			msg.retry = withTitle(title, msg.retry)
+			return msg
+		case sessionExpiredMsg:
+			msg.title = title
+			replay := msg.replay
+			msg.replay = func(jwtToken string) tea.Cmd {
+				return withTitle(title, replay(jwtToken))
+			}
+			return msg
+		default:
+			return msg
+		}
+	}
+}
+
 // Tasks starts with !
 // e.g. !start !stop
 func requestSendTask(taskName, jwtToken string) tea.Cmd {
 	return func() tea.Msg {
-		data := map[string]string{"task": taskName}
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			log.Fatalf("Error marshalling JSON: %v", err)
-		}
-
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-
-		client := &http.Client{Transport: transport}
-
-		url := fmt.Sprintf(cli.Args.Address("task"))
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
+		sentAt := time.Now()
+		output, err := api.Task(jwtToken, taskName)
+		rt := measure(sentAt, err)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!" + taskName,
+				task:  true,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestSendTask(taskName, jwtToken)
+				},
+			}
 		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-
-		resp, err := client.Do(req)
-		if err != nil {
-			panic(err.Error())
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title:     "!" + taskName,
+				err:       err,
+				retry:     requestSendTask(taskName, jwtToken),
+				roundTrip: rt,
+			}
 		}
-		defer resp.Body.Close()
-
-		// if resp.StatusCode != 200 {
-		// 	log.Printf("Session expired: login again")
-		// 	return sessionExpiredMsg("Session expired: login again")
-		// }
-		body, err := io.ReadAll(resp.Body)
-		// Response may contain newlines or spaces
-		// Who knows
-		body = []byte(strings.Trim(string(body), " \n"))
 
 		var msg taskFinishedMsg
 		msg.title = "!" + taskName
-		msg.msg = fmt.Sprintf("%d %s", resp.StatusCode, body)
+		msg.roundTrip = rt
+		msg.msg = fmt.Sprintf("%d %s", http.StatusOK, output)
 		msg.sucess = true
-		if resp.StatusCode != 200 {
-			msg.msg = fmt.Sprintf("%s", body)
+		if err != nil {
+			log.Printf("Task %s failed: %v", taskName, err)
+			msg.msg = errorText(err)
 			msg.sucess = false
 		}
 
@@ -355,47 +912,38 @@ func requestSendTask(taskName, jwtToken string) tea.Cmd {
 
 func requestSendCommand(command, jwtToken string) tea.Cmd {
 	return func() tea.Msg {
-		data := map[string]string{"command": command}
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			log.Fatalf("Error marshalling JSON: %v", err)
+		sentAt := time.Now()
+		output, err := api.Command(jwtToken, command)
+		rt := measure(sentAt, err)
+		title := command
+		if title == "" {
+			title = "<empty>"
 		}
-
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: title,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestSendCommand(command, jwtToken)
+				},
+			}
 		}
-
-		client := &http.Client{Transport: transport}
-
-		url := fmt.Sprintf(cli.Args.Address("command"))
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title:     title,
+				err:       err,
+				retry:     requestSendCommand(command, jwtToken),
+				roundTrip: rt,
+			}
 		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", jwtToken))
-
-		resp, err := client.Do(req)
 		if err != nil {
-			panic(err.Error())
+			log.Printf("Bad command %s: %v", command, err)
+			return commandOutputMsg{command: title, output: errorText(err), failed: true, roundTrip: rt}
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != 200 {
-			log.Printf("Bad command: %s", command)
-			// return sessionExpiredMsg("session expired: login again")
-		}
-		body, err := io.ReadAll(resp.Body)
-
-		if command == "help" && resp.StatusCode == 200 {
-			return commandOutputMsg{command, cleanHelpOutput(string(body))}
-		}
-		if command == "" {
-			command = "<empty>"
+		if command == "help" {
+			return commandOutputMsg{command: title, output: cleanHelpOutput(output), roundTrip: rt}
 		}
-		return commandOutputMsg{command, string(body)}
+		return commandOutputMsg{command: title, output: output, roundTrip: rt}
 	}
 }
 
@@ -2,6 +2,7 @@ package app
 
 import (
 	"testing"
+	"time"
 )
 
 func TestWrapCommandOutput(t *testing.T) {
@@ -40,3 +41,31 @@ func TestWrapCommandOutput(t *testing.T) {
 		}
 	}
 }
+
+func TestGutter(t *testing.T) {
+	at := time.Date(2024, 5, 6, 14, 3, 21, 0, time.Local)
+	tests := []struct {
+		entry    commandOutputMsg
+		expected string
+	}{
+		{
+			entry:    commandOutputMsg{roundTrip: roundTrip{sentAt: at, duration: 35 * time.Millisecond, status: 200}},
+			expected: "14:03:21 • 35ms • 200",
+		},
+		{
+			entry:    commandOutputMsg{task: true, roundTrip: roundTrip{sentAt: at, duration: 5 * time.Second}},
+			expected: "14:03:21 • 5s • no answer • task",
+		},
+		{
+			// Client side commands
+			entry:    commandOutputMsg{receivedAt: at},
+			expected: "14:03:21",
+		},
+	}
+
+	for _, tc := range tests {
+		if result := tc.entry.gutter(); result != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, result)
+		}
+	}
+}
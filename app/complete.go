@@ -0,0 +1,210 @@
+package app
+
+import (
+	"log"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"mctui/colors"
+	"mctui/grammar"
+	"mctui/mcformat"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Players join and leave, so the list gets old fast
+const completionMaxAge = 30 * time.Second
+
+// Client side commands and tasks known without asking the server
+var builtinCommands = []string{"!backup", "!restore", "!logout", "!export", "!run", "!schedule", "!players", "!dashboard"}
+
+// Replaced by the file in the config dir, see Connect
+var commandGrammar = grammar.Default()
+
+// Target selectors accepted by most commands
+var targetSelectors = []string{"@a", "@e", "@p", "@r", "@s"}
+
+// Tab completion for the command prompt
+// Candidates are shown in a line above the prompt
+type completer struct {
+	// Parsed from the help output
+	commands []string
+	// Parsed from the list output
+	players   []string
+	fetchedAt time.Time
+	fetching  bool
+
+	// Input without the word being completed
+	base string
+	// Input before the completion started, restored with esc
+	original   string
+	candidates []string
+	selected   int
+}
+
+// Send with the data used to complete
+type completionDataMsg struct {
+	commands []string
+	players  []string
+}
+
+func (c completer) active() bool {
+	return len(c.candidates) > 0
+}
+
+// Data is fetched in the background, so the first Tab may use old data
+func (c completer) stale() bool {
+	return !c.fetching && time.Since(c.fetchedAt) > completionMaxAge
+}
+
+// Starts a completion for input, or moves to the next candidate
+// Returns the new input
+func (c *completer) next(input string, backwards bool) string {
+	if !c.active() {
+		c.start(input)
+		switch len(c.candidates) {
+		case 0:
+			return input
+		case 1:
+			// Nothing to choose
+			completed := c.base + c.candidates[0] + " "
+			c.close()
+			return completed
+		}
+		c.selected = 0
+		if backwards {
+			c.selected = len(c.candidates) - 1
+		}
+		return c.base + c.candidates[c.selected]
+	}
+
+	step := 1
+	if backwards {
+		step = -1
+	}
+	c.selected = (c.selected + step + len(c.candidates)) % len(c.candidates)
+	return c.base + c.candidates[c.selected]
+}
+
+func (c *completer) start(input string) {
+	c.original = input
+	i := strings.LastIndex(input, " ")
+	c.base = input[:i+1]
+	word := input[i+1:]
+
+	var source []string
+	if i < 0 {
+		// Players may type /gamemode out of habit
+		if strings.HasPrefix(word, "/") {
+			c.base = "/"
+			word = word[1:]
+		}
+		source = append(source, c.commands...)
+		source = append(source, builtinCommands...)
+		source = append(source, slices.Sorted(maps.Keys(userMacros.Aliases))...)
+		source = append(source, slices.Sorted(maps.Keys(userMacros.Macros))...)
+	} else {
+		source = c.arguments(grammar.Split(input[:i]))
+	}
+
+	c.candidates = nil
+	for _, candidate := range source {
+		if strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(word)) &&
+			!slices.Contains(c.candidates, candidate) {
+			c.candidates = append(c.candidates, candidate)
+		}
+	}
+}
+
+// Candidates for an argument. args are the words before it
+func (c *completer) arguments(args []string) []string {
+	next, ok := commandGrammar.Next(args)
+	if !ok {
+		// Plugin command, players are a good guess
+		next = []grammar.Arg{{Type: grammar.Target}}
+	}
+
+	var source []string
+	for _, arg := range next {
+		switch arg.Type {
+		case grammar.Literal:
+			source = append(source, arg.Name)
+		case grammar.Enum:
+			source = append(source, arg.Values...)
+		case grammar.Bool:
+			source = append(source, "true", "false")
+		case grammar.Target:
+			source = append(source, c.players...)
+			source = append(source, targetSelectors...)
+		}
+	}
+	return source
+}
+
+// Keeps the input as it is
+func (c *completer) close() {
+	c.candidates = nil
+	c.selected = 0
+}
+
+// Returns the input before the completion started
+func (c *completer) cancel() string {
+	original := c.original
+	c.close()
+	return original
+}
+
+// One line with the candidates, scrolled to keep the selected visible
+func (c completer) View(width int) string {
+	selectedStyle := lipgloss.NewStyle().Foreground(colors.Pink).Bold(true)
+	candidateStyle := lipgloss.NewStyle().Foreground(colors.Surface2)
+
+	var parts []string
+	used := 0
+	for i := c.selected; i < len(c.candidates); i++ {
+		part := candidateStyle.Render(c.candidates[i])
+		if i == c.selected {
+			part = selectedStyle.Render(c.candidates[i])
+		}
+		used += lipgloss.Width(part) + 2
+		if used > width && len(parts) > 0 {
+			parts = append(parts, candidateStyle.Render("…"))
+			break
+		}
+		parts = append(parts, part)
+	}
+	if c.selected > 0 {
+		parts = append([]string{candidateStyle.Render("…")}, parts...)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// Fetches command names and online players for the completion
+// Errors are ignored, the completion just has less to offer
+func requestCompletionData(jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		var msg completionDataMsg
+
+		help, err := api.Command(jwtToken, "help")
+		if err != nil {
+			log.Printf("Can't fetch commands for completion: %v", err)
+		} else {
+			for _, name := range strings.Split(cleanHelpOutput(mcformat.Strip(help)), ", ") {
+				if name != "" {
+					msg.commands = append(msg.commands, name)
+				}
+			}
+		}
+
+		list, err := api.Command(jwtToken, "list")
+		if err != nil {
+			log.Printf("Can't fetch players for completion: %v", err)
+		} else {
+			_, _, msg.players = parsePlayerList(mcformat.Strip(list))
+		}
+		return msg
+	}
+}
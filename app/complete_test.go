@@ -0,0 +1,34 @@
+package app
+
+import "testing"
+
+func TestCompleter(t *testing.T) {
+	c := completer{
+		commands: []string{"gamemode", "gamerule", "give", "say"},
+		players:  []string{"alice", "bob"},
+	}
+
+	if got := c.next("sa", false); got != "say " || c.active() {
+		t.Errorf("Single candidate: got %q", got)
+	}
+
+	if got := c.next("/gam", false); got != "/gamemode" {
+		t.Errorf("First candidate: got %q", got)
+	}
+	if got := c.next("/gamemode", false); got != "/gamerule" {
+		t.Errorf("Next candidate: got %q", got)
+	}
+	if got := c.next("/gamerule", false); got != "/gamemode" {
+		t.Errorf("Wrap around: got %q", got)
+	}
+	if got := c.cancel(); got != "/gam" {
+		t.Errorf("Cancel: got %q", got)
+	}
+
+	if got := c.next("give B", false); got != "give bob " {
+		t.Errorf("Player: got %q", got)
+	}
+	if got := c.next("tp @", true); got != "tp @s" {
+		t.Errorf("Selector backwards: got %q", got)
+	}
+}
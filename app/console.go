@@ -0,0 +1,346 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mctui/client"
+	"mctui/colors"
+	"mctui/console"
+	"mctui/mcformat"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Tabs of the command screen, the prompt is shared
+const (
+	historyTab = iota
+	consoleTab
+	chatTab
+)
+
+var tabNames = []string{"History", "Console", "Chat"}
+
+// Older lines are dropped
+const maxConsoleLines = 5000
+
+// Longest wait between reconnections
+const maxLogBackoff = 30 * time.Second
+
+// Server console, followed while the client runs
+// Shared by the copies of the command model, like stepRun
+type consoleStream struct {
+	lines []console.Line
+	// Keeps the view still, lines are still received
+	paused bool
+	// Lines received while paused
+	unseen int
+	// Scrolls to the new lines
+	follow   bool
+	minLevel console.Level
+	status   string
+	// Failed connections in a row, for the backoff
+	attempts int
+	// The server doesn't stream logs, don't try again
+	unsupported bool
+	ctx         context.Context
+	stop        context.CancelFunc
+}
+
+// Lines read from an open stream
+type logConn struct {
+	lines chan string
+	// Why the stream ended, set before lines is closed
+	err error
+}
+
+type logConnectedMsg struct {
+	stream *consoleStream
+	conn   *logConn
+}
+
+type logLinesMsg struct {
+	stream *consoleStream
+	conn   *logConn
+	lines  []string
+}
+
+type logClosedMsg struct {
+	stream *consoleStream
+	err    error
+}
+
+type logReconnectMsg struct {
+	stream *consoleStream
+}
+
+func (logConnectedMsg) background() {}
+func (logLinesMsg) background()     {}
+func (logClosedMsg) background()    {}
+func (logReconnectMsg) background() {}
+
+func newConsoleStream() *consoleStream {
+	ctx, stop := context.WithCancel(context.Background())
+	return &consoleStream{follow: true, minLevel: console.Info, status: "connecting", ctx: ctx, stop: stop}
+}
+
+func (s *consoleStream) add(raw []string) {
+	previous := console.Info
+	if len(s.lines) > 0 {
+		previous = s.lines[len(s.lines)-1].Level
+	}
+	for _, r := range raw {
+		line := console.Parse(mcformat.Strip(r), previous)
+		s.lines = append(s.lines, line)
+		previous = line.Level
+	}
+	if len(s.lines) > maxConsoleLines {
+		s.lines = s.lines[len(s.lines)-maxConsoleLines:]
+	}
+	if s.paused {
+		s.unseen += len(raw)
+	}
+}
+
+// 1s, 2s, 4s... up to maxLogBackoff
+func logBackoff(attempts int) time.Duration {
+	d := time.Second << min(attempts-1, 5)
+	return min(d, maxLogBackoff)
+}
+
+// Opens the stream and reads it in the background
+func connectLogs(stream *consoleStream, jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		logs, err := api.Logs(stream.ctx, jwtToken)
+		if err != nil {
+			return logClosedMsg{stream: stream, err: err}
+		}
+		conn := &logConn{lines: make(chan string, 256)}
+		go func() {
+			defer logs.Close()
+			for {
+				line, err := logs.Next()
+				if err != nil {
+					conn.err = err
+					close(conn.lines)
+					return
+				}
+				select {
+				case conn.lines <- line:
+				case <-stream.ctx.Done():
+					conn.err = stream.ctx.Err()
+					close(conn.lines)
+					return
+				}
+			}
+		}()
+		return logConnectedMsg{stream: stream, conn: conn}
+	}
+}
+
+// Waits for the next lines. A burst is read at once, so it's rendered once
+func readLogs(stream *consoleStream, conn *logConn) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-conn.lines
+		if !ok {
+			return logClosedMsg{stream: stream, err: conn.err}
+		}
+		lines := []string{line}
+		for len(lines) < cap(conn.lines) {
+			select {
+			case line, ok := <-conn.lines:
+				if !ok {
+					// Closed msg comes with the next read
+					return logLinesMsg{stream: stream, conn: conn, lines: lines}
+				}
+				lines = append(lines, line)
+			default:
+				return logLinesMsg{stream: stream, conn: conn, lines: lines}
+			}
+		}
+		return logLinesMsg{stream: stream, conn: conn, lines: lines}
+	}
+}
+
+// Switches tabs, the console connects the first time it or the chat is shown
+func (m commandModel) showTab(tab int) (commandModel, tea.Cmd) {
+	m.tab = tab
+	m.commandInput.Placeholder = commandPlaceholder
+	if tab == chatTab {
+		m.commandInput.Placeholder = "message for the players"
+	}
+	if tab == historyTab || m.console != nil {
+		return m, nil
+	}
+	m.console = newConsoleStream()
+	m.consoleView = newTabView()
+	m.chatView = newTabView()
+	m = m.resize()
+	return m, connectLogs(m.console, m.jwtToken)
+}
+
+func newTabView() viewport.Model {
+	v := viewport.New(0, 0)
+	// Arrows belong to the prompt
+	v.KeyMap = viewport.KeyMap{}
+	v.MouseWheelEnabled = true
+	return v
+}
+
+// Keys of the Console tab. Elsewhere alt+f moves the prompt cursor by word
+func (m commandModel) updateConsoleKeys(msg tea.KeyMsg) (commandModel, bool) {
+	s := m.console
+	switch msg.String() {
+	case "alt+f":
+		s.follow = !s.follow
+		if s.follow {
+			m.consoleView.GotoBottom()
+		}
+	case "alt+p":
+		s.paused = !s.paused
+		if !s.paused {
+			s.unseen = 0
+			m = m.updateConsoleContent()
+		}
+	case "alt+l":
+		s.minLevel = (s.minLevel + 1) % (console.Error + 1)
+		m = m.updateConsoleContent()
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+func (m commandModel) updateConsole(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case logConnectedMsg:
+		if m.console != msg.stream {
+			return m, nil
+		}
+		log.Printf("Console connected")
+		m.console.status = "connected"
+		return m, readLogs(msg.stream, msg.conn)
+
+	case logLinesMsg:
+		if m.console != msg.stream {
+			return m, nil
+		}
+		m.console.attempts = 0
+		m.console.add(msg.lines)
+		if !m.console.paused {
+			m = m.updateConsoleContent()
+		}
+		return m, readLogs(msg.stream, msg.conn)
+
+	case logClosedMsg:
+		s := msg.stream
+		if m.console != s || errors.Is(s.ctx.Err(), context.Canceled) {
+			return m, nil
+		}
+		if client.IsNotFound(msg.err) {
+			s.unsupported = true
+			s.status = "the server doesn't stream logs"
+			return m, nil
+		}
+		// The same token would be rejected again, the stream waits for the login
+		if client.IsUnauthorized(msg.err) {
+			log.Printf("Console rejected: %v", msg.err)
+			s.status = "session expired"
+			s.attempts = 0
+			return m, expiredCmd(sessionExpiredMsg{
+				title: "console",
+				replay: func(jwtToken string) tea.Cmd {
+					s.status = "connecting"
+					return connectLogs(s, jwtToken)
+				},
+			})
+		}
+		s.attempts++
+		delay := logBackoff(s.attempts)
+		reason := "closed"
+		if msg.err != nil {
+			reason = errorText(msg.err)
+		}
+		log.Printf("Console disconnected: %s. Retry in %s", reason, delay)
+		s.status = fmt.Sprintf("disconnected: %s • retry in %s", reason, delay)
+		return m, tea.Tick(delay, func(time.Time) tea.Msg {
+			return logReconnectMsg{stream: s}
+		})
+
+	case logReconnectMsg:
+		if m.console != msg.stream {
+			return m, nil
+		}
+		m.console.status = "connecting"
+		return m, connectLogs(msg.stream, m.jwtToken)
+	}
+	return m, nil
+}
+
+func (m commandModel) updateConsoleContent() commandModel {
+	if m.console == nil {
+		return m
+	}
+	width := m.consoleView.Width
+	var lines []string
+	for _, line := range m.console.lines {
+		if line.Level < m.console.minLevel {
+			continue
+		}
+		style := lipgloss.NewStyle().Foreground(colors.Text)
+		switch line.Level {
+		case console.Debug:
+			style = style.Foreground(colors.Surface2)
+		case console.Warn:
+			style = style.Foreground(colors.Pink)
+		case console.Error:
+			style = style.Foreground(colors.Red)
+		}
+		lines = append(lines, style.Width(width).Render(line.Raw))
+	}
+	m.consoleView.SetContent(strings.Join(lines, "\n"))
+	if m.console.follow {
+		m.consoleView.GotoBottom()
+	}
+	return m.updateChatContent()
+}
+
+// e.g. History │ Console      connected • follow • INFO+
+func (m commandModel) tabsView() string {
+	var tabs []string
+	for i, name := range tabNames {
+		style := lipgloss.NewStyle().Foreground(colors.Surface2)
+		if i == m.tab {
+			style = style.Foreground(colors.Pink).Bold(true)
+		}
+		tabs = append(tabs, style.Render(name))
+	}
+	left := strings.Join(tabs, lipgloss.NewStyle().Foreground(colors.Surface1).Render(" │ "))
+
+	s := m.console
+	parts := []string{s.status}
+	if s.follow {
+		parts = append(parts, "follow")
+	}
+	parts = append(parts, s.minLevel.String()+"+")
+	if s.paused {
+		parts = append(parts, fmt.Sprintf("paused (%d new)", s.unseen))
+	}
+	statusStyle := lipgloss.NewStyle().Foreground(colors.Surface1)
+	if strings.HasPrefix(s.status, "disconnected") || s.unsupported {
+		statusStyle = statusStyle.Foreground(colors.Red)
+	}
+	right := statusStyle.Render(strings.Join(parts, " • "))
+
+	space := m.width - lipgloss.Width(left) - lipgloss.Width(right)
+	if space < 1 {
+		return lipgloss.NewStyle().MaxWidth(m.width).Render(left + " " + right)
+	}
+	return left + strings.Repeat(" ", space) + right
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"mctui/client"
+	"mctui/console"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestConsole(t *testing.T) {
+	setupFakeServer(t)
+	m := InitialCommandModel(nil, testToken, 80, 24)
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = model.(commandModel)
+
+	m, cmd := m.showTab(consoleTab)
+	if m.console == nil || m.consoleView.Height != m.viewport.Height {
+		t.Fatalf("Expected the console to open")
+	}
+	model = m
+	// Read until the fake server closes the stream
+	var msg tea.Msg
+	for {
+		msg = cmd()
+		if _, closed := msg.(logClosedMsg); closed {
+			break
+		}
+		model, cmd = model.Update(msg)
+		if cmd == nil {
+			t.Fatalf("Expected to keep reading after %T", msg)
+		}
+	}
+	m = model.(commandModel)
+	if len(m.console.lines) != len(consoleLines) {
+		t.Fatalf("Expected %d lines, got %+v", len(consoleLines), m.console.lines)
+	}
+	if trace := m.console.lines[3]; trace.Level != console.Error {
+		t.Errorf("Stack trace should keep the error level, got %v", trace.Level)
+	}
+
+	// Reconnects with a growing backoff
+	model, cmd = m.Update(msg)
+	m = model.(commandModel)
+	if cmd == nil || m.console.attempts != 1 {
+		t.Errorf("Expected a reconnection, got %d attempts", m.console.attempts)
+	}
+	model, cmd = m.Update(logReconnectMsg{stream: m.console})
+	if _, ok := cmd().(logConnectedMsg); !ok {
+		t.Errorf("Expected to connect again")
+	}
+
+	// A rejected token waits for the login instead of retrying
+	unauthorized := &client.StatusError{StatusCode: http.StatusUnauthorized}
+	_, cmd = m.Update(logClosedMsg{stream: m.console, err: unauthorized})
+	expired, ok := cmd().(sessionExpiredMsg)
+	if !ok || m.console.status != "session expired" {
+		t.Fatalf("Expected the session to expire, got %q", m.console.status)
+	}
+	if _, ok := expired.replay(testToken)().(logConnectedMsg); !ok {
+		t.Errorf("Expected the replay to connect again")
+	}
+
+	// Older servers don't have logs
+	notFound := &client.StatusError{StatusCode: http.StatusNotFound}
+	model, cmd = model.Update(logClosedMsg{stream: m.console, err: notFound})
+	if cmd != nil || !model.(commandModel).console.unsupported {
+		t.Errorf("Expected to give up")
+	}
+}
+
+func TestLogBackoff(t *testing.T) {
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, d := range expected {
+		if got := logBackoff(i + 1); got != d {
+			t.Errorf("Attempt %d: expected %s, got %s", i+1, d, got)
+		}
+	}
+}
+
+func TestConsoleFilter(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 80, 24)
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m, _ = model.(commandModel).showTab(consoleTab)
+	m.console.add(consoleLines)
+	m = m.updateConsoleContent()
+	if lines := m.consoleView.TotalLineCount(); lines != 3 {
+		t.Errorf("Debug should be hidden, got %d lines", lines)
+	}
+
+	// Info, warn, error
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l"), Alt: true})
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l"), Alt: true})
+	m = model.(commandModel)
+	if m.console.minLevel != console.Error || m.consoleView.TotalLineCount() != 2 {
+		t.Errorf("Expected errors only, got %d lines", m.consoleView.TotalLineCount())
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p"), Alt: true})
+	m = model.(commandModel)
+	model, _ = m.Update(logLinesMsg{stream: m.console, conn: &logConn{lines: make(chan string)}, lines: []string{"[12:00:03] [Server thread/ERROR]: again"}})
+	m = model.(commandModel)
+	if m.console.unseen != 1 || m.consoleView.TotalLineCount() != 2 {
+		t.Errorf("Paused view should not change")
+	}
+
+	// Other tabs leave alt+f to the prompt
+	m, _ = m.showTab(historyTab)
+	m.commandInput.SetValue("say hello")
+	m.commandInput.SetCursor(0)
+	follow := m.console.follow
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f"), Alt: true})
+	m = model.(commandModel)
+	if m.console.follow != follow || m.commandInput.Position() != 3 {
+		t.Errorf("Expected the cursor to move by word, got %d", m.commandInput.Position())
+	}
+}
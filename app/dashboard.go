@@ -0,0 +1,498 @@
+package app
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"mctui/client"
+	"mctui/colors"
+	"mctui/config"
+	"mctui/mcformat"
+	"mctui/perf"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// [dashboard] section of the config file
+var dashboardConfig config.Dashboard
+
+// Must be called before running the program
+func SetDashboard(d config.Dashboard) {
+	dashboardConfig = d
+}
+
+// Older samples are dropped, 2h with the default interval
+const maxPerfSamples = 720
+
+// Tried in order until one answers. debug writes a report
+// in the server folder on every poll, so it must be asked for
+var perfSources = []string{"metrics", "tps", "forge"}
+
+var errNoPerfSource = errors.New("no performance numbers")
+
+// Performance numbers, polled in the background once the dashboard is opened
+// Shared by the copies of the command model, like stepRun
+type perfMonitor struct {
+	// Empty until a source answers
+	source   string
+	samples  []perf.Sample
+	polledAt time.Time
+	err      string
+	// No source answered, r tries again
+	stopped bool
+}
+
+// Time to poll again
+type perfPollMsg struct {
+	monitor *perfMonitor
+}
+
+type perfSampleMsg struct {
+	monitor *perfMonitor
+	source  string
+	sample  perf.Sample
+	err     error
+}
+
+func (perfPollMsg) background()   {}
+func (perfSampleMsg) background() {}
+
+func dashboardInterval() time.Duration {
+	if dashboardConfig.Interval > 0 {
+		return time.Duration(dashboardConfig.Interval) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// The polls don't go through requestSendCommand, they would fill the history
+func requestPerfSample(monitor *perfMonitor, jwtToken string) tea.Cmd {
+	source := cmp.Or(monitor.source, dashboardConfig.Source)
+	return func() tea.Msg {
+		sources := perfSources
+		if source != "" {
+			sources = []string{source}
+		}
+		for _, s := range sources {
+			sample, ok, err := samplePerf(s, jwtToken)
+			if err != nil {
+				log.Printf("Can't poll %s: %v", s, err)
+				return perfSampleMsg{monitor: monitor, err: err}
+			}
+			if ok {
+				return perfSampleMsg{monitor: monitor, source: s, sample: sample}
+			}
+		}
+		err := fmt.Errorf("%w from %s", errNoPerfSource, source)
+		if source == "" {
+			err = fmt.Errorf("%w, the server has no /metrics, tps or forge tps", errNoPerfSource)
+		}
+		return perfSampleMsg{monitor: monitor, err: err}
+	}
+}
+
+// Returns false if the server doesn't know the source
+func samplePerf(source, jwtToken string) (perf.Sample, bool, error) {
+	sample := perf.NewSample(time.Now())
+	command := func(command string) (string, error) {
+		output, err := api.Command(jwtToken, command)
+		return mcformat.Strip(output), err
+	}
+
+	switch source {
+	case "metrics":
+		m, err := api.Metrics(jwtToken)
+		if client.IsNotFound(err) {
+			return sample, false, nil
+		}
+		if err != nil {
+			return sample, false, err
+		}
+		if m.TPS != nil {
+			sample.TPS = *m.TPS
+		}
+		if m.MSPT != nil {
+			sample.MSPT = *m.MSPT
+		}
+		if m.HeapUsed != nil {
+			sample.HeapUsed = float64(*m.HeapUsed) / (1 << 20)
+		}
+		if m.HeapMax != nil {
+			sample.HeapMax = float64(*m.HeapMax) / (1 << 20)
+		}
+		if m.LoadedChunks != nil {
+			sample.Chunks = float64(*m.LoadedChunks)
+		}
+		return sample, true, nil
+
+	case "tps":
+		output, err := command("tps")
+		if err != nil || !perf.ParseTPS(output, &sample) {
+			return sample, false, err
+		}
+		// Only Paper has mspt
+		if output, err := command("mspt"); err == nil {
+			perf.ParseMSPT(output, &sample)
+		}
+		return sample, true, nil
+
+	case "forge":
+		output, err := command("forge tps")
+		if err != nil {
+			return sample, false, err
+		}
+		return sample, perf.ParseForgeTPS(output, &sample), nil
+
+	case "debug":
+		// Numbers of the last interval, then profile the next one
+		// Fails harmlessly the first time, when nothing is profiled
+		output, err := command("debug stop")
+		if err != nil {
+			return sample, false, err
+		}
+		perf.ParseDebug(output, &sample)
+		output, err = command("debug start")
+		if err != nil {
+			return sample, false, err
+		}
+		return sample, strings.Contains(output, "Started"), nil
+	}
+	return sample, false, fmt.Errorf("%w, unknown source %q", errNoPerfSource, source)
+}
+
+// Returns false when the polls stop
+func (p *perfMonitor) update(msg perfSampleMsg, now time.Time) bool {
+	p.polledAt = now
+	switch {
+	case errors.Is(msg.err, errNoPerfSource):
+		p.err = msg.err.Error()
+		p.stopped = true
+		return false
+	case client.IsUnauthorized(msg.err):
+		p.err = "session expired"
+	case msg.err != nil:
+		p.err = fmt.Sprintf("can't reach the server: %s", errorText(msg.err))
+	default:
+		p.source = msg.source
+		p.err = ""
+		if !msg.sample.Empty() {
+			p.add(msg.sample)
+		}
+	}
+	return true
+}
+
+func (p *perfMonitor) add(sample perf.Sample) {
+	// Keep a trace of the lag in the log file
+	for _, c := range perfCharts() {
+		v := c.value(sample)
+		if c.bad(v) && (len(p.samples) == 0 || !c.bad(c.value(p.samples[len(p.samples)-1]))) {
+			log.Printf("%s is %s (%s)", c.name, fmt.Sprintf(c.format, v), c.limit)
+		}
+	}
+	p.samples = append(p.samples, sample)
+	if len(p.samples) > maxPerfSamples {
+		p.samples = p.samples[len(p.samples)-maxPerfSamples:]
+	}
+}
+
+// Starts the polls the first time and shows the dashboard
+func (m commandModel) openDashboard() (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	if m.perf == nil {
+		m.perf = &perfMonitor{}
+		cmd = requestPerfSample(m.perf, m.jwtToken)
+	}
+	newModel := InitialDashboardModel(m, m.jwtToken, m.width, m.height)
+	return newModel, tea.Batch(cmd, newModel.Init())
+}
+
+func (m commandModel) updatePerf(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case perfPollMsg:
+		if m.perf != msg.monitor {
+			return m, nil
+		}
+		return m, requestPerfSample(msg.monitor, m.jwtToken)
+
+	case perfSampleMsg:
+		if m.perf != msg.monitor || !m.perf.update(msg, time.Now()) {
+			return m, nil
+		}
+		// The screen on top asks the password, then the polls go on
+		if client.IsUnauthorized(msg.err) {
+			monitor := msg.monitor
+			return m, expiredCmd(sessionExpiredMsg{
+				title: "dashboard",
+				replay: func(jwtToken string) tea.Cmd {
+					return requestPerfSample(monitor, jwtToken)
+				},
+			})
+		}
+		return m, tea.Tick(dashboardInterval(), func(time.Time) tea.Msg {
+			return perfPollMsg{monitor: msg.monitor}
+		})
+	}
+	return m, nil
+}
+
+// One line of numbers and its bars
+type perfChart struct {
+	name  string
+	value func(perf.Sample) float64
+	// Latest value, may show more than value
+	text   func(perf.Sample) string
+	format string
+	// Range of the bars, high is raised by bigger values
+	low  float64
+	high float64
+	// Red values, TPS is bad when low
+	bad   func(float64) bool
+	limit string
+}
+
+func perfCharts() []perfChart {
+	c := dashboardConfig
+	minTPS := cmp.Or(c.MinTPS, 18)
+	maxMSPT := cmp.Or(c.MaxMSPT, 50)
+	maxHeap := cmp.Or(c.MaxHeap, 90)
+	above := func(limit float64) func(float64) bool {
+		return func(v float64) bool { return limit > 0 && v > limit }
+	}
+
+	chunks := perfChart{
+		name:   "Chunks",
+		value:  func(s perf.Sample) float64 { return s.Chunks },
+		text:   func(s perf.Sample) string { return fmt.Sprintf("%.0f", s.Chunks) },
+		format: "%.0f",
+		high:   c.MaxChunks,
+		bad:    above(c.MaxChunks),
+	}
+	if c.MaxChunks > 0 {
+		chunks.limit = fmt.Sprintf("red above %g", c.MaxChunks)
+	}
+
+	return []perfChart{
+		{
+			name:   "TPS",
+			value:  func(s perf.Sample) float64 { return s.TPS },
+			text:   func(s perf.Sample) string { return fmt.Sprintf("%.1f", s.TPS) },
+			format: "%.1f",
+			// Anything below is lag anyway
+			low:   10,
+			high:  20,
+			bad:   func(v float64) bool { return v < minTPS },
+			limit: fmt.Sprintf("red below %g", minTPS),
+		},
+		{
+			name:   "MSPT",
+			value:  func(s perf.Sample) float64 { return s.MSPT },
+			text:   func(s perf.Sample) string { return fmt.Sprintf("%.1f ms", s.MSPT) },
+			format: "%.1f ms",
+			high:   maxMSPT,
+			bad:    above(maxMSPT),
+			limit:  fmt.Sprintf("red above %g ms", maxMSPT),
+		},
+		{
+			name:   "Heap",
+			value:  perf.Sample.HeapPercent,
+			text:   heapText,
+			format: "%.0f%%",
+			high:   100,
+			bad:    above(maxHeap),
+			limit:  fmt.Sprintf("red above %g%%", maxHeap),
+		},
+		chunks,
+	}
+}
+
+// e.g. 1.5 GB / 4.0 GB (37%)
+func heapText(s perf.Sample) string {
+	size := func(mb float64) string {
+		if mb >= 1024 {
+			return fmt.Sprintf("%.1f GB", mb/1024)
+		}
+		return fmt.Sprintf("%.0f MB", mb)
+	}
+	if math.IsNaN(s.HeapMax) {
+		return size(s.HeapUsed)
+	}
+	return fmt.Sprintf("%s / %s (%.0f%%)", size(s.HeapUsed), size(s.HeapMax), s.HeapPercent())
+}
+
+// Charts of the performance numbers, shown with F3 or !dashboard
+// The numbers belong to the command model, so they survive the screen
+type dashboardModel struct {
+	prevModel tea.Model
+	jwtToken  string
+	width     int
+	height    int
+	// Shown when the session expires
+	reauth screenReauth
+}
+
+func InitialDashboardModel(prevModel tea.Model, jwtToken string, width, height int) dashboardModel {
+	return dashboardModel{prevModel: prevModel, jwtToken: jwtToken, width: width, height: height}
+}
+
+func (m dashboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m dashboardModel) monitor() *perfMonitor {
+	if prev, ok := m.prevModel.(commandModel); ok && prev.perf != nil {
+		return prev.perf
+	}
+	return &perfMonitor{}
+}
+
+func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Ask the password here, the replies come back to this screen
+	if next, cmd, ok := m.reauth.update(msg, &m.prevModel, &m.jwtToken); ok {
+		if next == nil {
+			next = m
+		}
+		return next, cmd
+	}
+
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			return m.prevModel.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		case "r":
+			// Polls run already unless they stopped
+			p := m.monitor()
+			if p.stopped {
+				p.stopped = false
+				p.err = ""
+				return m, func() tea.Msg { return perfPollMsg{monitor: p} }
+			}
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case backgroundMsg:
+		m.prevModel, cmd = m.prevModel.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m dashboardModel) View() string {
+	if view, ok := m.reauth.view(m.width, m.height); ok {
+		return view
+	}
+	h, _ := docStyle.GetFrameSize()
+	width := max(m.width-h, 10)
+	p := m.monitor()
+
+	parts := []string{lipgloss.NewStyle().Foreground(colors.Pink).Bold(true).Render("Dashboard")}
+	for _, c := range perfCharts() {
+		parts = append(parts, "", m.chartView(c, p, width))
+	}
+	parts = append(parts, "", m.statusView(p, width))
+	return docStyle.Render(lipgloss.JoinVertical(lipgloss.Left, parts...))
+}
+
+// e.g. TPS     19.8      worst 17.2 • red below 18
+//
+//	▇▇▇█▇▆▇▇▇▇
+func (m dashboardModel) chartView(c perfChart, p *perfMonitor, width int) string {
+	dim := lipgloss.NewStyle().Foreground(colors.Surface2)
+	name := lipgloss.NewStyle().Foreground(colors.Text).Bold(true).Width(8).Render(c.name)
+
+	var values []float64
+	worst := math.NaN()
+	for _, s := range p.samples {
+		v := c.value(s)
+		values = append(values, v)
+		if math.IsNaN(v) {
+			continue
+		}
+		// TPS is the only one where low is bad
+		if math.IsNaN(worst) || (c.name == "TPS" && v < worst) || (c.name != "TPS" && v > worst) {
+			worst = v
+		}
+	}
+	if math.IsNaN(worst) {
+		text := "waiting for the first numbers"
+		if p.source != "" {
+			text = "not reported by " + p.source
+		}
+		return name + dim.Render(text) + "\n"
+	}
+
+	latest := p.samples[len(p.samples)-1]
+	valueStyle := lipgloss.NewStyle().Foreground(colors.Green).Width(24)
+	current := "n/a"
+	if v := c.value(latest); !math.IsNaN(v) {
+		current = c.text(latest)
+		if c.bad(v) {
+			valueStyle = valueStyle.Foreground(colors.Red).Bold(true)
+		}
+	}
+	stats := []string{"worst " + fmt.Sprintf(c.format, worst)}
+	if c.limit != "" {
+		stats = append(stats, c.limit)
+	}
+	header := name + valueStyle.Render(current) + dim.Render(strings.Join(stats, " • "))
+
+	// Newest on the right, one bar per poll
+	values = values[max(len(values)-width, 0):]
+	high := c.high
+	for _, v := range values {
+		if v > high {
+			high = v
+		}
+	}
+	bars := perf.Sparkline(values, c.low, high)
+
+	// Same colors are rendered together
+	var line strings.Builder
+	start := 0
+	for i := 1; i <= len(bars); i++ {
+		if i < len(bars) && c.bad(values[i]) == c.bad(values[start]) {
+			continue
+		}
+		color := colors.Green
+		if c.bad(values[start]) {
+			color = colors.Red
+		}
+		line.WriteString(lipgloss.NewStyle().Foreground(color).Render(string(bars[start:i])))
+		start = i
+	}
+	return header + "\n" + line.String()
+}
+
+// e.g. tps every 10s • 42 polls since 12:00:00 • last poll 12:07:00 • esc back
+func (m dashboardModel) statusView(p *perfMonitor, width int) string {
+	style := lipgloss.NewStyle().Foreground(colors.Surface2).MaxWidth(width)
+	var parts []string
+	if p.source != "" {
+		parts = append(parts, fmt.Sprintf("%s every %s", p.source, dashboardInterval()))
+	}
+	if len(p.samples) > 0 {
+		parts = append(parts, fmt.Sprintf("%d polls since %s", len(p.samples), p.samples[0].Time.Format(time.TimeOnly)))
+	}
+	if !p.polledAt.IsZero() {
+		parts = append(parts, "last poll "+p.polledAt.Format(time.TimeOnly))
+	}
+	if p.stopped {
+		parts = append(parts, "r retry")
+	}
+	parts = append(parts, "esc back")
+	status := style.Render(strings.Join(parts, " • "))
+	if p.err != "" {
+		status = style.Foreground(colors.Red).Render(p.err) + "\n" + status
+	}
+	return status
+}
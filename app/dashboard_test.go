@@ -0,0 +1,150 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"mctui/config"
+	"mctui/mcformat"
+	"mctui/perf"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestDashboard(t *testing.T) {
+	setupFakeServer(t)
+	m := InitialCommandModel(nil, testToken, 100, 30)
+	model, cmd := m.openDashboard()
+	dashboard := model.(dashboardModel)
+	p := dashboard.monitor()
+	if cmd == nil || p.source != "" {
+		t.Fatalf("Expected a first poll")
+	}
+
+	// No /metrics on the fake server, tps answers
+	model, cmd = dashboard.Update(cmd())
+	dashboard = model.(dashboardModel)
+	if p.source != "tps" || len(p.samples) != 1 || p.samples[0].TPS != 19.5 || p.samples[0].MSPT != 12.3 {
+		t.Fatalf("Unexpected monitor %+v", p)
+	}
+	if cmd == nil {
+		t.Errorf("Expected the next poll")
+	}
+
+	view := mcformat.Strip(dashboard.View())
+	for _, want := range []string{"19.5", "12.3 ms", "not reported by tps", "tps every 10s"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the view:\n%s", want, view)
+		}
+	}
+
+	// Samples of an old monitor are ignored
+	model, cmd = dashboard.Update(perfSampleMsg{monitor: &perfMonitor{}, source: "tps", sample: p.samples[0]})
+	if len(p.samples) != 1 || cmd != nil {
+		t.Errorf("Old monitor changed the samples")
+	}
+	if len(model.(dashboardModel).prevModel.(commandModel).history) != 0 {
+		t.Errorf("Polls should not reach the history")
+	}
+}
+
+func TestDashboardReauth(t *testing.T) {
+	setupFakeServer(t)
+	m := InitialCommandModel(nil, expiredToken, 100, 30)
+	model, cmd := m.openDashboard()
+	p := model.(dashboardModel).monitor()
+
+	// The rejected poll goes back to the screen on top
+	model, cmd = model.Update(cmd())
+	if cmd == nil || p.err != "session expired" {
+		t.Fatalf("Expected the expired session to be sent on, got %+v", p)
+	}
+	model, _ = model.Update(cmd())
+	dashboard, ok := model.(dashboardModel)
+	if !ok || !dashboard.reauth.active() {
+		t.Fatalf("Expected the password prompt on the dashboard, got %T", model)
+	}
+	if !strings.Contains(dashboard.View(), "Session expired") {
+		t.Errorf("Expected the prompt in the view")
+	}
+
+	model, cmd = model.Update(authMsg{username: "admin", jwtToken: testToken, sucess: true})
+	dashboard, ok = model.(dashboardModel)
+	if !ok || dashboard.reauth.active() || dashboard.jwtToken != testToken {
+		t.Fatalf("Expected to stay on the dashboard with the new token")
+	}
+	if token := dashboard.prevModel.(commandModel).jwtToken; token != testToken {
+		t.Errorf("Expected the command screen to get the new token, got %q", token)
+	}
+	_, cmd = model.Update(cmd())
+	if len(p.samples) != 1 || p.err != "" || cmd == nil {
+		t.Errorf("Expected the poll to be replayed and go on, got %+v", p)
+	}
+}
+
+func TestDashboardNoSource(t *testing.T) {
+	setupFakeServer(t)
+	t.Cleanup(func() { dashboardConfig = config.Dashboard{} })
+	SetDashboard(config.Dashboard{Source: "forge"})
+
+	m := InitialCommandModel(nil, testToken, 100, 30)
+	m.perf = &perfMonitor{}
+	model, cmd := m.Update(requestPerfSample(m.perf, testToken)())
+	m = model.(commandModel)
+	if !m.perf.stopped || cmd != nil {
+		t.Fatalf("Expected the polls to stop, got %+v", m.perf)
+	}
+	if !strings.Contains(m.perf.err, "from forge") {
+		t.Errorf("Unexpected error %q", m.perf.err)
+	}
+
+	dashboard := InitialDashboardModel(m, testToken, 100, 30)
+	_, cmd = dashboard.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if cmd == nil || m.perf.stopped {
+		t.Errorf("Expected r to poll again")
+	}
+}
+
+func TestPerfCharts(t *testing.T) {
+	t.Cleanup(func() { dashboardConfig = config.Dashboard{} })
+
+	tests := []struct {
+		config config.Dashboard
+		sample perf.Sample
+		bad    []string
+	}{
+		{sample: perf.Sample{TPS: 19, MSPT: 40, HeapUsed: 800, HeapMax: 1000, Chunks: 9000}},
+		{sample: perf.Sample{TPS: 17, MSPT: 60, HeapUsed: 950, HeapMax: 1000, Chunks: 9000}, bad: []string{"TPS", "MSPT", "Heap"}},
+		{
+			config: config.Dashboard{MinTPS: 19.5, MaxMSPT: 30, MaxHeap: 75, MaxChunks: 5000},
+			sample: perf.Sample{TPS: 19, MSPT: 40, HeapUsed: 800, HeapMax: 1000, Chunks: 9000},
+			bad:    []string{"TPS", "MSPT", "Heap", "Chunks"},
+		},
+	}
+
+	for _, tc := range tests {
+		SetDashboard(tc.config)
+		var bad []string
+		for _, c := range perfCharts() {
+			if c.bad(c.value(tc.sample)) {
+				bad = append(bad, c.name)
+			}
+		}
+		if strings.Join(bad, ",") != strings.Join(tc.bad, ",") {
+			t.Errorf("%+v: expected %v to be red, got %v", tc.config, tc.bad, bad)
+		}
+	}
+}
+
+func TestHeapText(t *testing.T) {
+	s := perf.NewSample(time.Now())
+	s.HeapUsed = 512
+	if got := heapText(s); got != "512 MB" {
+		t.Errorf("Unexpected %q", got)
+	}
+	s.HeapUsed, s.HeapMax = 1536, 4096
+	if got := heapText(s); got != "1.5 GB / 4.0 GB (38%)" {
+		t.Errorf("Unexpected %q", got)
+	}
+}
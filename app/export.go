@@ -0,0 +1,62 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mctui/mcformat"
+	"mctui/transcript"
+)
+
+// Writes the history to path, the format comes from the extension
+// Without a path, writes Markdown to the current directory
+// The result is added to the history, but not to the file
+func (m commandModel) export(path string) commandModel {
+	if path == "" {
+		name := strings.NewReplacer(":", "-", "/", "-").Replace(serverName())
+		path = fmt.Sprintf("mctui-%s-%s.md", name, time.Now().Format("20060102-150405"))
+	}
+	path = expandHome(path)
+
+	var entries []transcript.Entry
+	for _, e := range m.history {
+		at := e.sentAt
+		if at.IsZero() {
+			at = e.receivedAt
+		}
+		entries = append(entries, transcript.Entry{
+			Command:    e.command,
+			Output:     mcformat.Strip(e.output),
+			Time:       at,
+			Task:       e.task,
+			Failed:     e.failed,
+			Status:     e.status,
+			DurationMS: e.duration.Milliseconds(),
+		})
+	}
+
+	title := fmt.Sprintf("mctui transcript: %s", serverName())
+	result := commandOutputMsg{command: "!export " + path}
+	if err := transcript.WriteFile(path, title, entries); err != nil {
+		log.Printf("Can't export history: %v", err)
+		result.output = fmt.Sprintf("can't export: %v", err)
+		result.failed = true
+	} else {
+		result.output = fmt.Sprintf("%d entries written to %s", len(entries), path)
+	}
+	return m.appendHistory(result)
+}
+
+// Paths typed in the prompt don't go through the shell
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
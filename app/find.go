@@ -0,0 +1,181 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"mctui/colors"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Search in the output history, opened with ctrl+f
+type viewportSearch struct {
+	input textinput.Model
+	// Typing the query. Otherwise n/N move between matches
+	typing bool
+	// Line of each match in the viewport content
+	matches []int
+	// Index in matches, -1 selects the newest
+	current int
+}
+
+func newViewportSearch() *viewportSearch {
+	input := textinput.New()
+	input.Prompt = "find: "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(colors.Pink)
+	input.Focus()
+	return &viewportSearch{input: input, typing: true, current: -1}
+}
+
+// Case insensitive, the query is not a regex
+func (s *viewportSearch) pattern() *regexp.Regexp {
+	if s.input.Value() == "" {
+		return nil
+	}
+	return regexp.MustCompile("(?i)" + regexp.QuoteMeta(s.input.Value()))
+}
+
+// Returns content with the matches highlighted and updates the matches
+// Lines with matches lose their colors, the escape codes would break the search
+func (s *viewportSearch) highlight(content string) string {
+	s.matches = nil
+	pattern := s.pattern()
+	if pattern == nil {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	found := make([][][]int, len(lines))
+	for i, line := range lines {
+		found[i] = pattern.FindAllStringIndex(ansi.Strip(line), -1)
+		for range found[i] {
+			s.matches = append(s.matches, i)
+		}
+	}
+	if s.current < 0 || s.current >= len(s.matches) {
+		s.current = len(s.matches) - 1
+	}
+
+	textStyle := lipgloss.NewStyle().Foreground(colors.Text)
+	matchStyle := lipgloss.NewStyle().Foreground(colors.Surface0).Background(colors.Surface2)
+	currentStyle := lipgloss.NewStyle().Foreground(colors.Surface0).Background(colors.Pink)
+
+	n := 0
+	for i, line := range lines {
+		if len(found[i]) == 0 {
+			continue
+		}
+		plain := ansi.Strip(line)
+		var b strings.Builder
+		last := 0
+		for _, match := range found[i] {
+			style := matchStyle
+			if n == s.current {
+				style = currentStyle
+			}
+			b.WriteString(textStyle.Render(plain[last:match[0]]))
+			b.WriteString(style.Render(plain[match[0]:match[1]]))
+			last = match[1]
+			n++
+		}
+		b.WriteString(textStyle.Render(plain[last:]))
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Handles the keys while the search is open
+// Returns false when the key closes the search and must be handled by the prompt
+func (m commandModel) updateFind(msg tea.KeyMsg) (commandModel, tea.Cmd, bool) {
+	s := m.find
+	if msg.Type == tea.KeyEscape {
+		m.find = nil
+		m = m.updateViewportContent()
+		return m, nil, true
+	}
+
+	if s.typing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			s.typing = false
+			s.input.Blur()
+			return m.scrollToMatch(), nil, true
+		case tea.KeyCtrlC:
+			return m, tea.Quit, true
+		}
+		var cmd tea.Cmd
+		s.input, cmd = s.input.Update(msg)
+		s.current = -1
+		m = m.updateViewportContent()
+		return m.scrollToMatch(), cmd, true
+	}
+
+	switch msg.String() {
+	case "n":
+		if len(s.matches) > 0 {
+			s.current = (s.current + 1) % len(s.matches)
+		}
+	case "N":
+		if len(s.matches) > 0 {
+			s.current = (s.current - 1 + len(s.matches)) % len(s.matches)
+		}
+	case "/", "ctrl+f":
+		s.typing = true
+		return m, s.input.Focus(), true
+	case "up", "k":
+		m.viewport.LineUp(1)
+		return m, nil, true
+	case "down", "j":
+		m.viewport.LineDown(1)
+		return m, nil, true
+	case "pgup":
+		m.viewport.HalfViewUp()
+		return m, nil, true
+	case "pgdown":
+		m.viewport.HalfViewDown()
+		return m, nil, true
+	case "g":
+		m.viewport.GotoTop()
+		return m, nil, true
+	case "G":
+		m.viewport.GotoBottom()
+		return m, nil, true
+	default:
+		// Back to typing commands
+		m.find = nil
+		m = m.updateViewportContent()
+		return m, nil, false
+	}
+	m = m.updateViewportContent()
+	return m.scrollToMatch(), nil, true
+}
+
+// Centers the current match
+func (m commandModel) scrollToMatch() commandModel {
+	s := m.find
+	if s.current >= 0 && s.current < len(s.matches) {
+		m.viewport.SetYOffset(s.matches[s.current] - m.viewport.Height/2)
+	}
+	return m
+}
+
+func (m commandModel) findView() string {
+	s := m.find
+	hintStyle := lipgloss.NewStyle().Foreground(colors.Surface2)
+
+	count := "no matches"
+	if len(s.matches) > 0 {
+		count = fmt.Sprintf("%d/%d", s.current+1, len(s.matches))
+	}
+	keys := "enter done • esc close"
+	if !s.typing {
+		keys = "n next • N prev • / edit • esc close"
+	}
+	view := fmt.Sprintf("%s  %s", s.input.View(), hintStyle.Render(count+" • "+keys))
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(view)
+}
@@ -0,0 +1,32 @@
+package app
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestViewportSearchHighlight(t *testing.T) {
+	s := newViewportSearch()
+	s.input.SetValue("Alice")
+	content := "list\nThere are 2 players online: alice, bob\nsay hi\n[Server] hi alice and ALICE"
+
+	result := s.highlight(content)
+	if ansi.Strip(result) != content {
+		t.Errorf("Highlight changed the text:\n%s", ansi.Strip(result))
+	}
+	if !slices.Equal(s.matches, []int{1, 3, 3}) {
+		t.Errorf("Unexpected matches %v", s.matches)
+	}
+	// Starts at the newest match
+	if s.current != 2 {
+		t.Errorf("Expected current 2, got %d", s.current)
+	}
+
+	s.input.SetValue("nothing")
+	s.highlight(content)
+	if len(s.matches) != 0 || s.current != -1 {
+		t.Errorf("Expected no matches, got %v %d", s.matches, s.current)
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"mctui/cli"
+	"mctui/client"
+	"mctui/macro"
+	"mctui/script"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Non-interactive mode, used by the subcommands
+// Commands go through parseCommand, so they behave like in the TUI
+
+var errSessionExpired = errors.New("session expired")
+
+// Returns a token without asking anything
+// Tries the stored session, the client certificate, then the password
+func headlessLogin(password string, skipStored bool) (string, error) {
+	if !skipStored {
+		if token, ok := storedToken(); ok {
+			return token, nil
+		}
+	}
+
+	if cli.Args.HasClientCert() {
+		err := api.CheckAuth("")
+		if err == nil {
+			return "", nil
+		}
+		if client.StatusCode(err) == 0 {
+			return "", headlessError(err)
+		}
+	}
+
+	if cli.Args.Username == "" || password == "" {
+		return "", fmt.Errorf("no valid session: set --username and --password (or MCTUI_PASSWORD)")
+	}
+	token, err := api.Login(cli.Args.Username, password)
+	if err != nil {
+		return "", headlessError(err)
+	}
+	saveToken(cli.Args.Username, token)
+	return token, nil
+}
+
+// Turns the result of a command or task into its output
+func headlessOutput(msg tea.Msg) (string, error) {
+	switch msg := msg.(type) {
+	case commandOutputMsg:
+		if msg.failed {
+			return msg.output, fmt.Errorf("%s failed: %s", msg.command, msg.output)
+		}
+		return msg.output, nil
+	case taskFinishedMsg:
+		if !msg.sucess {
+			return msg.msg, fmt.Errorf("%s failed: %s", msg.title, msg.msg)
+		}
+		return msg.msg, nil
+	default:
+		return "", fmt.Errorf("unexpected result %T", msg)
+	}
+}
+
+// Login shared by the requests of a subcommand
+// Scripts and macros login once, not once per step
+type headlessSession struct {
+	password string
+	token    string
+	// headlessLogin was called
+	loggedIn bool
+}
+
+func newHeadlessSession(password string) *headlessSession {
+	return &headlessSession{password: password}
+}
+
+// Runs the request, waiting for its message. The first one logs in
+// The stored session may be expired on the server, so we login once more
+func (s *headlessSession) request(request func(jwtToken string) tea.Cmd) (tea.Msg, error) {
+	if !s.loggedIn {
+		if cli.Args.Logout {
+			forgetServerTokens()
+		}
+		token, err := headlessLogin(s.password, cli.Args.Logout)
+		if err != nil {
+			return nil, err
+		}
+		s.token, s.loggedIn = token, true
+	}
+
+	msg := request(s.token)()
+	if _, ok := msg.(sessionExpiredMsg); ok {
+		log.Printf("Stored session rejected, login again")
+		forgetToken(s.token)
+		token, err := headlessLogin(s.password, true)
+		if err != nil {
+			return nil, err
+		}
+		s.token = token
+		msg = request(token)()
+	}
+
+	switch msg := msg.(type) {
+	case sessionExpiredMsg:
+		return nil, errSessionExpired
+	case requestErrorMsg:
+		return nil, headlessError(msg.err)
+	}
+	return msg, nil
+}
+
+// Exec runs a RCON command or task and prints its output
+// Aliases and macros are expanded, the steps run one after the other
+func Exec(w io.Writer, command, password string) error {
+	steps, ok, err := userMacros.Expand(command)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := script.CheckCommand(command); err != nil {
+			return err
+		}
+		steps = []macro.Step{{Command: command}}
+	}
+	session := newHeadlessSession(password)
+	for _, step := range steps {
+		if step.IsWait() {
+			log.Printf("Wait %s", step.Wait)
+			time.Sleep(step.Wait)
+			continue
+		}
+		if err := execCommand(w, step.Command, session); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func execCommand(w io.Writer, command string, session *headlessSession) error {
+	msg, err := session.request(func(jwtToken string) tea.Cmd {
+		return parseCommand(nil, command, jwtToken)
+	})
+	if err != nil {
+		return err
+	}
+	output, err := headlessOutput(msg)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintln(w, output)
+	}
+	return nil
+}
+
+// Adds a hint when the user must act on the TUI first
+func headlessError(err error) error {
+	var unknownCert *client.UnknownCertError
+	if errors.As(err, &unknownCert) {
+		return fmt.Errorf("%w\nrun mctui once to trust it, or use --pin-sha256", err)
+	}
+	return err
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+
+	"mctui/cli"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+const backupTimeLayout = "2006-01-02 15:04:05"
+
+// BackupCreate makes a backup of the current save
+func BackupCreate(w io.Writer, password string) error {
+	return Exec(w, "!backup", password)
+}
+
+// BackupList prints the backups, newest first
+func BackupList(w io.Writer, password string, asJSON bool) error {
+	backups, err := headlessBackups(newHeadlessSession(password))
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		type jsonBackup struct {
+			Filename string `json:"filename"`
+			// Offset by --time-offset, so it has no time zone
+			Time string `json:"time"`
+		}
+		out := []jsonBackup{}
+		for _, b := range backups {
+			out = append(out, jsonBackup{
+				Filename: b.Filename,
+				Time:     b.Time.Format("2006-01-02T15:04:05"),
+			})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, b := range backups {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", b.Filename, b.Time.Format(backupTimeLayout), b.timeHumanized())
+	}
+	return tw.Flush()
+}
+
+// BackupRestore restores the backup with this filename
+// "latest" restores the newest one
+func BackupRestore(w io.Writer, password, filename string) error {
+	session := newHeadlessSession(password)
+	backups, err := headlessBackups(session)
+	if err != nil {
+		return err
+	}
+
+	var selected *backup
+	for _, b := range backups {
+		if b.Filename == filename || (filename == "latest" && selected == nil) {
+			selected = &b
+		}
+	}
+	if selected == nil {
+		return fmt.Errorf("backup %s not found", filename)
+	}
+
+	msg, err := session.request(func(jwtToken string) tea.Cmd {
+		return requestRestoreBackup(selected.Filename, jwtToken)
+	})
+	if err != nil {
+		return err
+	}
+	output, err := headlessOutput(msg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%s %s (%s)\n", output, selected.Filename, humanize.Time(selected.Time))
+	return nil
+}
+
+// Fetches the backups using the same request as the backup screen
+// Times are offset by --time-offset
+func headlessBackups(session *headlessSession) ([]backup, error) {
+	msg, err := session.request(fetchData)
+	if err != nil {
+		return nil, err
+	}
+	fetched, ok := msg.(fetchMsg)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result %T", msg)
+	}
+
+	offset := cli.Args.TimeOffset()
+	var backups []backup
+	for _, item := range fetched.items {
+		backups = append(backups, item.(backup).OffsetBy(offset))
+	}
+	slices.SortFunc(backups, func(a, b backup) int {
+		return b.Time.Compare(a.Time)
+	})
+	return backups, nil
+}
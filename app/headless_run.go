@@ -0,0 +1,70 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"mctui/script"
+)
+
+// Run executes a script without the TUI
+// Confirms read the answer from in, unless yes is set
+func Run(w io.Writer, in io.Reader, path string, args []string, vars map[string]string, password string, yes bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s, err := script.Parse(path, f)
+	if err != nil {
+		return err
+	}
+
+	session := newHeadlessSession(password)
+	answers := bufio.NewReader(in)
+	runner := s.Runner(args, vars)
+	failed := 0
+	for {
+		step, ok, err := runner.Next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		switch step.Kind {
+		case script.Sleep:
+			log.Printf("Sleep %s", step.Sleep)
+			time.Sleep(step.Sleep)
+		case script.Confirm:
+			fmt.Fprintf(w, "%s (y/n) ", step.Prompt)
+			if yes {
+				fmt.Fprintln(w, "y")
+				continue
+			}
+			answer, _ := answers.ReadString('\n')
+			if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+				return fmt.Errorf("%s:%d: cancelled", path, step.Line)
+			}
+		default:
+			fmt.Fprintf(w, "> %s\n", step.Command)
+			if err := execCommand(w, step.Command, session); err != nil {
+				if !runner.ContinueOnError() {
+					return fmt.Errorf("%s:%d: %w", path, step.Line, err)
+				}
+				fmt.Fprintf(w, "error: %v\n", err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d commands failed", failed)
+	}
+	return nil
+}
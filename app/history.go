@@ -0,0 +1,137 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"mctui/cli"
+	"mctui/colors"
+	"mctui/history"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Returns the history file of the current server and its commands
+// The file is nil with --no-history, so nothing is saved
+func openHistory() (*history.File, []string) {
+	f, err := history.Default(serverName())
+	if err != nil {
+		log.Printf("Can't open history: %v", err)
+		return nil, nil
+	}
+	commands, err := f.Load()
+	if err != nil {
+		log.Printf("Can't load history: %v", err)
+	}
+	if cli.Args.IsNoHistory() {
+		return nil, commands
+	}
+	return f, commands
+}
+
+// The profile, or host:port without one
+func serverName() string {
+	if cli.Args.Profile != "" {
+		return cli.Args.Profile
+	}
+	return fmt.Sprintf("%s:%d", cli.Args.Host, cli.Args.Port)
+}
+
+// Readline style incremental search, opened with ctrl+r
+type reverseSearch struct {
+	query string
+	// Index of the match in the commands, -1 without one
+	index int
+	// Input before the search, restored with esc
+	original string
+}
+
+func newReverseSearch(original string) *reverseSearch {
+	return &reverseSearch{index: -1, original: original}
+}
+
+// Looks for the query from index from to the oldest command
+func (s *reverseSearch) find(commands []string, from int) bool {
+	for i := min(from, len(commands)-1); i >= 0; i-- {
+		if strings.Contains(commands[i], s.query) {
+			s.index = i
+			return true
+		}
+	}
+	return false
+}
+
+// Handles the keys while searching
+// Returns false when the key ends the search and must be handled by the prompt
+func (m commandModel) updateSearch(msg tea.KeyMsg) (commandModel, bool) {
+	s := m.search
+	switch msg.Type {
+	case tea.KeyCtrlR:
+		// Next older match
+		if s.index < 0 {
+			s.find(m.commands, len(m.commands)-1)
+		} else {
+			s.find(m.commands, s.index-1)
+		}
+	case tea.KeyBackspace:
+		if s.query == "" {
+			return m, true
+		}
+		runes := []rune(s.query)
+		s.query = string(runes[:len(runes)-1])
+		s.index = -1
+		s.find(m.commands, len(m.commands)-1)
+	case tea.KeyRunes, tea.KeySpace:
+		if msg.Type == tea.KeySpace {
+			s.query += " "
+		} else {
+			s.query += string(msg.Runes)
+		}
+		from := s.index
+		if from < 0 {
+			from = len(m.commands) - 1
+		}
+		if !s.find(m.commands, from) {
+			s.index = -1
+		}
+	case tea.KeyEscape, tea.KeyCtrlG:
+		m.commandInput.SetValue(s.original)
+		m.commandInput.CursorEnd()
+		m.search = nil
+		return m, true
+	default:
+		// Keeps the match, e.g. enter runs it
+		m.search = nil
+		return m, false
+	}
+
+	if s.index >= 0 {
+		m.commandInput.SetValue(m.commands[s.index])
+	} else {
+		m.commandInput.SetValue("")
+	}
+	m.commandInput.CursorEnd()
+	return m, true
+}
+
+// Adds the command to the history, and to the file unless --no-history
+func (m commandModel) remember(command string) commandModel {
+	m.commands = history.Append(m.commands, command)
+	if m.historyFile != nil {
+		if err := m.historyFile.Add(command); err != nil {
+			log.Printf("Can't save history: %v", err)
+		}
+	}
+	return m
+}
+
+func (m commandModel) searchView() string {
+	style := lipgloss.NewStyle().MaxWidth(m.width).Foreground(colors.Surface2)
+	view := fmt.Sprintf("reverse search: %s", m.search.query)
+	if m.search.query != "" && m.search.index < 0 {
+		return style.Foreground(colors.Red).Render(view + " • no match")
+	}
+	return style.Render(view)
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestReverseSearch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 80, 24)
+	m.commands = []string{"say hello", "list", "say bye", "time set day"}
+
+	keys := []tea.KeyMsg{
+		{Type: tea.KeyCtrlR},
+		{Type: tea.KeyRunes, Runes: []rune("say")},
+	}
+	var model tea.Model = m
+	for _, key := range keys {
+		model, _ = model.Update(key)
+	}
+	if value := model.(commandModel).commandInput.Value(); value != "say bye" {
+		t.Errorf("Expected say bye, got %q", value)
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if value := model.(commandModel).commandInput.Value(); value != "say hello" {
+		t.Errorf("Expected say hello, got %q", value)
+	}
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	m = model.(commandModel)
+	if m.search != nil || m.commandInput.Value() != "" {
+		t.Errorf("Expected search cancelled, got %q", m.commandInput.Value())
+	}
+}
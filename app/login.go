@@ -1,19 +1,12 @@
 package app
 
 import (
-	"bytes"
-	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net"
-	"net/http"
-	"os"
-	"strings"
-	"time"
 
 	"mctui/cli"
+	"mctui/client"
 	"mctui/colors"
 
 	"github.com/charmbracelet/bubbles/textinput"
@@ -32,15 +25,24 @@ type loginModel struct {
 	width         int
 	height        int
 	err           error
+	// Server certificate waiting for the user to trust it
+	unknownCert *client.UnknownCertError
+	retryAuth   tea.Cmd
 }
 
 // Send after login attempt
 type authMsg struct {
+	username string
 	jwtToken string
 	sucess   bool
 	err      error
+	// Makes the same attempt again, e.g. after trusting the certificate
+	retry tea.Cmd
 }
 
+// The client certificate isn't enough, the password form stays as it is
+type passwordNeededMsg struct{}
+
 func InitialLoginModel() loginModel {
 	ui := textinput.New()
 	ui.Placeholder = "username"
@@ -63,6 +65,10 @@ func InitialLoginModel() loginModel {
 	// Use for debug only
 	ui.SetValue("admin")
 	pi.SetValue("adminpass123")
+	if cli.Args.Username != "" {
+		ui.SetValue(cli.Args.Username)
+		pi.SetValue("")
+	}
 
 	return loginModel{
 		usernameInput: ui,
@@ -72,7 +78,24 @@ func InitialLoginModel() loginModel {
 	}
 }
 
+// First screen of the program
+// Skips the login if there is a valid session from a previous run
+func InitialModel() tea.Model {
+	login := InitialLoginModel()
+	if cli.Args.Logout {
+		forgetServerTokens()
+		return login
+	}
+	if token, ok := storedToken(); ok {
+		return InitialCommandModel(login, token, 0, 0)
+	}
+	return login
+}
+
 func (m loginModel) Init() tea.Cmd {
+	if cli.Args.HasClientCert() {
+		return tea.Batch(textinput.Blink, tea.ClearScreen, requestCertificateAuth)
+	}
 	return tea.Batch(textinput.Blink, tea.ClearScreen)
 }
 
@@ -86,6 +109,10 @@ func (m loginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 
+		if m.unknownCert != nil {
+			return m.updateTrustPrompt(msg)
+		}
+
 		if m.err != nil {
 			// When user press any key on the error screen
 			m.err = nil
@@ -128,16 +155,28 @@ func (m loginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case authMsg:
+		// First connection to a server with a self-signed certificate
+		var unknownCert *client.UnknownCertError
+		if errors.As(msg.err, &unknownCert) {
+			log.Printf("Unknown certificate: %v", unknownCert)
+			m.unknownCert = unknownCert
+			m.retryAuth = msg.retry
+			return m, nil
+		}
 		if msg.err != nil {
 			m.err = fmt.Errorf("Can't login: %v", msg.err)
 			log.Printf("%v", m.err)
 			// return m, tea.Quit
 		}
 		// Clear forms now. Then, when session expires, it is already clear
-		m.clearForm()
+		m = m.clearForm()
 
 		// Authentication works
 		if msg.sucess {
+			// No token when authenticated by the client certificate
+			if msg.jwtToken != "" {
+				saveToken(msg.username, msg.jwtToken)
+			}
 			newModel := InitialCommandModel(m, msg.jwtToken, m.width, m.height)
 			// Init is called when on tea.NewProgram()
 			// Since we are initializing it by ourself, we need to trigger it manually
@@ -146,6 +185,10 @@ func (m loginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Bad credentials
 		return m, nil
 
+	// Keep the username of the profile
+	case passwordNeededMsg:
+		return m, nil
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -160,6 +203,25 @@ func (m loginModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// Trust on first use
+// The certificate is stored in the known hosts, then we try to login again
+func (m loginModel) updateTrustPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		fingerprint := m.unknownCert.Fingerprint
+		m.unknownCert = nil
+		if err := api.Trust(fingerprint); err != nil {
+			m.err = fmt.Errorf("Can't trust certificate: %v", err)
+			return m, nil
+		}
+		return m, m.retryAuth
+	case "n", "N", "esc":
+		m.unknownCert = nil
+		m.err = fmt.Errorf("Certificate rejected by user")
+	}
+	return m, nil
+}
+
 func (m loginModel) clearForm() loginModel {
 	m.usernameInput.Focus()
 	m.usernameInput.SetValue("")
@@ -187,7 +249,37 @@ func (m loginModel) ErrorView() string {
 	return centerWrapper.Render(both)
 }
 
+func (m loginModel) TrustView() string {
+	centerWrapper := lipgloss.NewStyle().Align(lipgloss.Center, lipgloss.Center).Width(m.width - 2).Height(m.height - 2)
+
+	title := lipgloss.NewStyle().
+		Foreground(colors.Pink).
+		Bold(true).
+		Render(fmt.Sprintf("Unknown certificate for %s", m.unknownCert.Host))
+
+	description := lipgloss.NewStyle().
+		Foreground(colors.Surface2).
+		Align(lipgloss.Center).
+		Render(wordwrap.String(
+			"\nThe server certificate is not signed by a trusted authority.\n"+
+				"Compare its fingerprint with the one on the server before trusting it.\n", m.width-12))
+
+	fingerprint := lipgloss.NewStyle().
+		Foreground(colors.Text).
+		Render(wordwrap.String(client.FormatFingerprint(m.unknownCert.Fingerprint), m.width-12))
+
+	prompt := lipgloss.NewStyle().
+		Foreground(colors.Pink).
+		Render("\nTrust this certificate? (y/n)")
+
+	all := lipgloss.JoinVertical(lipgloss.Center, title, description, "SHA-256", fingerprint, prompt)
+	return centerWrapper.Render(all)
+}
+
 func (m loginModel) View() string {
+	if m.unknownCert != nil {
+		return m.TrustView()
+	}
 	if m.err != nil {
 		return m.ErrorView()
 	}
@@ -214,42 +306,37 @@ func (m loginModel) View() string {
 }
 
 func requestAuthenticateUser(username, password string) tea.Msg {
-	data := map[string]string{
-		"username": username,
-		"password": password,
-	}
-	jsonData, err := json.Marshal(data)
+	log.Printf("Making request to %s", api.URL("login"))
+	token, err := api.Login(username, password)
 	if err != nil {
-		log.Fatalf("Error marshalling JSON: %v", err)
-	}
-
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		DialContext: (&net.Dialer{
-			Timeout: 5 * time.Second, // Timeout for connection setup
-		}).DialContext,
-		TLSHandshakeTimeout: 5 * time.Second,
-	}
-
-	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
-	url := fmt.Sprintf(cli.Args.Address("login"))
-
-	log.Printf("Making request to %s", url)
-	resp, err := client.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		var errMsg error
-		if os.IsTimeout(err) {
-			errMsg = fmt.Errorf("timeout error: %w", err)
-		} else {
-			errMsg = fmt.Errorf("error making request: %w", err)
+		// Bad credentials
+		if client.StatusCode(err) != 0 {
+			log.Printf("Login failed: %v", err)
+			return authMsg{}
 		}
 		return authMsg{
-			err: errMsg,
+			err: err,
+			retry: func() tea.Msg {
+				return requestAuthenticateUser(username, password)
+			},
 		}
 	}
-	defer resp.Body.Close()
+	return authMsg{username: username, jwtToken: token, sucess: true}
+}
 
-	body, err := io.ReadAll(resp.Body)
-	trimmed := strings.TrimSpace(string(body))
-	return authMsg{jwtToken: trimmed, sucess: resp.Status == "200 OK"}
+// A proxy may authenticate us using the client certificate
+// In that case the server accepts requests without a token,
+// so we skip the password form
+func requestCertificateAuth() tea.Msg {
+	log.Printf("Checking client certificate authentication")
+	err := api.CheckAuth("")
+	if err != nil {
+		// Not authenticated by the certificate. Use the password form
+		if client.StatusCode(err) != 0 {
+			log.Printf("Certificate authentication failed: %v", err)
+			return passwordNeededMsg{}
+		}
+		return authMsg{err: err, retry: requestCertificateAuth}
+	}
+	return authMsg{sucess: true}
 }
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"testing"
+
+	"mctui/cli"
+)
+
+func TestPasswordNeeded(t *testing.T) {
+	previous := cli.Args
+	t.Cleanup(func() { cli.Args = previous })
+	cli.Args.Username = "alice"
+
+	model, _ := InitialLoginModel().Update(passwordNeededMsg{})
+	if value := model.(loginModel).usernameInput.Value(); value != "alice" {
+		t.Errorf("Expected the username to stay, got %q", value)
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"mctui/config"
+	"mctui/macro"
+	"mctui/script"
+)
+
+// Aliases and macros from the config file
+var userMacros macro.Set
+
+// Must be called before running the program
+func SetMacros(file *config.File) {
+	userMacros = macro.Set{Aliases: file.Aliases, Macros: file.Macros}
+}
+
+// Returns the command an alias stands for, or input if it's not one
+func expandAlias(input string) string {
+	steps, ok, err := userMacros.Expand(input)
+	if err != nil || !ok || len(steps) != 1 || steps[0].IsWait() {
+		return input
+	}
+	return steps[0].Command
+}
+
+// Runs the steps of a macro like a script
+// A failed step stops it
+type macroSteps struct {
+	name  string
+	steps []macro.Step
+	next  int
+}
+
+func (s *macroSteps) Name() string          { return s.name }
+func (s *macroSteps) ContinueOnError() bool { return false }
+
+func (s *macroSteps) Next() (script.Step, bool, error) {
+	if s.next >= len(s.steps) {
+		return script.Step{}, false, nil
+	}
+	step := s.steps[s.next]
+	s.next++
+	if step.IsWait() {
+		return script.Step{Kind: script.Sleep, Sleep: step.Wait}, true, nil
+	}
+	return script.Step{Kind: script.Command, Command: step.Command}, true, nil
+}
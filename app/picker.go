@@ -0,0 +1,182 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"mctui/cli"
+	"mctui/client"
+	"mctui/config"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/dustin/go-humanize"
+)
+
+// A profile from the config file
+type server struct {
+	name     string
+	profile  config.Profile
+	lastUsed time.Time
+	// Empty while the probe is running
+	status string
+}
+
+func (s server) Title() string { return s.name }
+func (s server) Description() string {
+	port := s.profile.Port
+	if port == 0 {
+		port = cli.Args.Port
+	}
+	host := s.profile.Host
+	if host == "" {
+		host = cli.Args.Host
+	}
+	if host == "" {
+		host = cli.DEFAULT_HOST
+	}
+
+	status := s.status
+	if status == "" {
+		status = "checking..."
+	}
+	used := "never used"
+	if !s.lastUsed.IsZero() {
+		used = fmt.Sprintf("used %s", humanize.Time(s.lastUsed))
+	}
+	return fmt.Sprintf("%s:%d • %s • %s", host, port, status, used)
+}
+func (s server) FilterValue() string { return s.name }
+
+// Server picker
+// Shown before the login when many profiles are configured
+type pickerModel struct {
+	list   list.Model
+	width  int
+	height int
+}
+
+// Send after checking if a server is reachable
+type probeMsg struct {
+	name   string
+	status string
+}
+
+func InitialPickerModel(file *config.File) pickerModel {
+	lastUsed, err := config.LastUsed()
+	if err != nil {
+		log.Printf("Can't load last used profiles: %v", err)
+	}
+
+	var items []list.Item
+	for _, name := range file.ProfileNames() {
+		items = append(items, server{
+			name:     name,
+			profile:  file.Profiles[name],
+			lastUsed: lastUsed[name],
+		})
+	}
+
+	m := pickerModel{
+		list: list.New(items, list.NewDefaultDelegate(), 0, 0),
+	}
+	m.list.Title = "Servers"
+	return m
+}
+
+func (m pickerModel) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	for _, item := range m.list.Items() {
+		cmds = append(cmds, probeServer(cli.Args, item.(server)))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		// Keys belong to the filter input while typing
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		switch msg.String() {
+		case "esc":
+			if m.list.FilterState() == list.Unfiltered {
+				return m, tea.Quit
+			}
+		case "enter":
+			s, ok := m.list.SelectedItem().(server)
+			if ok {
+				return m.connect(s)
+			}
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		h, v := docStyle.GetFrameSize()
+		m.list.SetSize(msg.Width-h, msg.Height-v)
+	case probeMsg:
+		for i, item := range m.list.Items() {
+			s := item.(server)
+			if s.name == msg.name {
+				s.status = msg.status
+				cmd := m.list.SetItem(i, s)
+				return m, cmd
+			}
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+// Uses the profile for the rest of the session
+func (m pickerModel) connect(s server) (tea.Model, tea.Cmd) {
+	log.Printf("Selected server %s", s.name)
+	previous := cli.Args
+	cli.Args.UseProfile(s.name, s.profile)
+	if err := Connect(); err != nil {
+		cli.Args = previous
+		cmd := m.list.NewStatusMessage(fmt.Sprintf("Can't use %s: %v", s.name, err))
+		return m, cmd
+	}
+
+	newModel := InitialModel()
+	newModel, cmd := newModel.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+	return newModel, tea.Batch(newModel.Init(), cmd)
+}
+
+func (m pickerModel) View() string {
+	return docStyle.Render(m.list.View())
+}
+
+// TLS handshake and a request to the login endpoint
+// Uses a copy of the args, the user may pick a server meanwhile
+func probeServer(args cli.CliArgs, s server) tea.Cmd {
+	return func() tea.Msg {
+		args.UseProfile(s.name, s.profile)
+		c, err := args.Client()
+		if err != nil {
+			return probeMsg{name: s.name, status: fmt.Sprintf("error: %v", err)}
+		}
+
+		latency, err := c.Probe()
+		var unknownCert *client.UnknownCertError
+		switch {
+		case err == nil:
+			return probeMsg{name: s.name, status: fmt.Sprintf("online %dms", latency.Milliseconds())}
+		case errors.As(err, &unknownCert):
+			return probeMsg{name: s.name, status: "online, unknown certificate"}
+		default:
+			log.Printf("Server %s is unreachable: %v", s.name, err)
+			return probeMsg{name: s.name, status: "offline"}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mctui/cli"
+	"mctui/colors"
+	"mctui/mcformat"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Vanilla: There are 2 of a max of 20 players online: alice, bob
+// Paper: There are 2 out of maximum 20 players online.
+var playerCountRegex = regexp.MustCompile(`(\d+)\D+?(\d+)\s+players online`)
+
+// Parses the output of the list command
+// Returns the number of players online, the max and their names
+func parsePlayerList(output string) (int, int, []string) {
+	var online, maxPlayers int
+	if match := playerCountRegex.FindStringSubmatch(output); match != nil {
+		online, _ = strconv.Atoi(match[1])
+		maxPlayers, _ = strconv.Atoi(match[2])
+	}
+
+	var names []string
+	_, after, found := strings.Cut(output, ":")
+	if !found {
+		return online, maxPlayers, names
+	}
+	for _, line := range strings.Split(after, "\n") {
+		// Paper groups players by rank, e.g. "default: alice, bob"
+		if _, grouped, ok := strings.Cut(line, ":"); ok {
+			line = grouped
+		}
+		for _, name := range strings.Split(line, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return online, maxPlayers, names
+}
+
+// Right-hand panel with the players online, shown with ctrl+p
+// Shared by the copies of the command model, like stepRun
+type playerPanel struct {
+	online     int
+	maxPlayers int
+	names      []string
+	// Changes since the previous poll
+	joined []string
+	left   []string
+	// Zero until the first answer
+	fetchedAt time.Time
+	err       string
+	// Arrows move in the panel instead of the history
+	focused  bool
+	selected int
+	// Quick actions for the selected player
+	menuOpen bool
+	action   int
+}
+
+// Fills the prompt, the user sends it with enter
+type playerAction struct {
+	name     string
+	key      string
+	template string
+}
+
+var playerActions = []playerAction{
+	{name: "kick", key: "k", template: "kick %s "},
+	{name: "ban", key: "b", template: "ban %s "},
+	{name: "tp", key: "t", template: "tp %s "},
+	{name: "gamemode", key: "g", template: "gamemode survival %s"},
+	{name: "op", key: "o", template: "op %s"},
+}
+
+// Width of the panel, border included
+const playersWidth = 26
+
+// Time to poll the player list again
+type playerPollMsg struct {
+	panel *playerPanel
+}
+
+// Answer of the list command
+type playerListMsg struct {
+	panel *playerPanel
+	msg   tea.Msg
+}
+
+func (playerPollMsg) background() {}
+func (playerListMsg) background() {}
+
+func playersInterval() time.Duration {
+	if cli.Args.PlayersInterval > 0 {
+		return time.Duration(cli.Args.PlayersInterval) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// Sends list like a typed command, but the answer goes to the panel
+func requestPlayers(panel *playerPanel, jwtToken string) tea.Cmd {
+	cmd := requestSendCommand("list", jwtToken)
+	return func() tea.Msg {
+		return playerListMsg{panel: panel, msg: cmd()}
+	}
+}
+
+func (p *playerPanel) update(msg tea.Msg, now time.Time) {
+	switch msg := msg.(type) {
+	case commandOutputMsg:
+		if msg.failed {
+			p.err = msg.output
+			return
+		}
+	case requestErrorMsg:
+		p.err = fmt.Sprintf("can't reach the server: %v", msg.err)
+		return
+	case sessionExpiredMsg:
+		p.err = "session expired"
+		return
+	default:
+		return
+	}
+
+	online, maxPlayers, names := parsePlayerList(mcformat.Strip(msg.(commandOutputMsg).output))
+	// Nothing is new in the first answer
+	if !p.fetchedAt.IsZero() {
+		p.joined = missing(names, p.names)
+		p.left = missing(p.names, names)
+	}
+	p.online, p.maxPlayers, p.names = online, maxPlayers, names
+	p.fetchedAt = now
+	p.err = ""
+	p.selected = clamp(p.selected, 0, max(len(names)-1, 0))
+	if len(names) == 0 {
+		p.menuOpen = false
+	}
+}
+
+// Returns the names in a that are not in b
+func missing(a, b []string) []string {
+	var names []string
+	for _, name := range a {
+		if !slices.Contains(b, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Shows the panel, focuses it, then hides it
+func (m commandModel) togglePlayers() (commandModel, tea.Cmd) {
+	switch {
+	case m.players == nil:
+		m.players = &playerPanel{focused: true}
+		return m.resize(), requestPlayers(m.players, m.jwtToken)
+	case !m.players.focused:
+		m.players.focused = true
+		return m, nil
+	default:
+		m.players = nil
+		return m.resize(), nil
+	}
+}
+
+// Keys while the panel is focused
+// Returns false for the keys that belong to the prompt
+func (m commandModel) updatePlayers(msg tea.KeyMsg) (commandModel, bool) {
+	p := m.players
+	if p.menuOpen {
+		switch msg.Type {
+		case tea.KeyUp:
+			p.action = clamp(p.action-1, 0, len(playerActions)-1)
+		case tea.KeyDown:
+			p.action = clamp(p.action+1, 0, len(playerActions)-1)
+		case tea.KeyEnter:
+			return m.fillAction(playerActions[p.action]), true
+		case tea.KeyEscape:
+			p.menuOpen = false
+		default:
+			for _, action := range playerActions {
+				if msg.String() == action.key {
+					return m.fillAction(action), true
+				}
+			}
+			return m, msg.Type == tea.KeyRunes
+		}
+		return m, true
+	}
+
+	switch msg.Type {
+	case tea.KeyUp:
+		p.selected = clamp(p.selected-1, 0, max(len(p.names)-1, 0))
+	case tea.KeyDown:
+		p.selected = clamp(p.selected+1, 0, max(len(p.names)-1, 0))
+	case tea.KeyEnter:
+		if len(p.names) > 0 {
+			p.menuOpen = true
+			p.action = 0
+		}
+	case tea.KeyEscape:
+		p.focused = false
+	default:
+		return m, false
+	}
+	return m, true
+}
+
+func (m commandModel) fillAction(action playerAction) commandModel {
+	p := m.players
+	p.menuOpen = false
+	p.focused = false
+	m.commandInput.SetValue(fmt.Sprintf(action.template, p.names[p.selected]))
+	m.commandInput.CursorEnd()
+	return m
+}
+
+func (m commandModel) playersView() string {
+	p := m.players
+	width := playersWidth - 2
+	line := lipgloss.NewStyle().MaxWidth(width)
+	title := line.Foreground(colors.Pink).Bold(true)
+	dim := line.Foreground(colors.Surface2)
+
+	var lines []string
+	if p.fetchedAt.IsZero() && p.err == "" {
+		lines = append(lines, title.Render("Players"), dim.Render("loading..."))
+	} else {
+		lines = append(lines, title.Render(fmt.Sprintf("Players %d/%d", p.online, p.maxPlayers)))
+	}
+	for i, name := range p.names {
+		style := line.Foreground(colors.Text)
+		prefix := "  "
+		if slices.Contains(p.joined, name) {
+			style = style.Foreground(colors.Green)
+			prefix = "+ "
+		}
+		if p.focused && i == p.selected {
+			style = style.Reverse(true)
+		}
+		lines = append(lines, style.Render(prefix+name))
+		if p.menuOpen && i == p.selected {
+			for j, action := range playerActions {
+				actionStyle := dim
+				if j == p.action {
+					actionStyle = line.Foreground(colors.Pink)
+				}
+				lines = append(lines, actionStyle.Render(fmt.Sprintf("    %s %s", action.key, action.name)))
+			}
+		}
+	}
+	for _, name := range p.left {
+		lines = append(lines, line.Foreground(colors.Red).Strikethrough(true).Render("- "+name))
+	}
+	if p.err != "" {
+		lines = append(lines, "", lipgloss.NewStyle().Width(width).Foreground(colors.Red).Render(p.err))
+	}
+
+	return lipgloss.NewStyle().
+		Width(playersWidth-1).
+		Height(m.viewport.Height).
+		MaxHeight(m.viewport.Height).
+		PaddingLeft(1).
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(colors.Surface1).
+		Render(strings.Join(lines, "\n"))
+}
+
+// Keys of the focused panel, shown above the prompt
+func (m commandModel) playersHintView() string {
+	style := lipgloss.NewStyle().MaxWidth(m.width).Foreground(colors.Surface2)
+	if m.players.menuOpen {
+		return style.Render("↑/↓ select • enter or letter fills the prompt • esc close")
+	}
+	return style.Render("↑/↓ select player • enter actions • esc back to the prompt • ctrl+p hide")
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPlayerPanel(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 100, 24)
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 24})
+	m = model.(commandModel)
+
+	m, cmd := m.togglePlayers()
+	if m.players == nil || !m.players.focused || cmd == nil {
+		t.Fatalf("Expected a focused panel and a poll")
+	}
+	if m.viewport.Width != 100-playersWidth {
+		t.Errorf("Expected the viewport to shrink, got %d", m.viewport.Width)
+	}
+	panel := m.players
+
+	list := func(output string) {
+		model, _ := m.Update(playerListMsg{panel: panel, msg: commandOutputMsg{command: "list", output: output}})
+		m = model.(commandModel)
+	}
+	list("There are 2 of a max of 20 players online: alice, bob")
+	if panel.joined != nil || panel.left != nil {
+		t.Errorf("First answer should not highlight, got %v %v", panel.joined, panel.left)
+	}
+	list("There are 2 of a max of 20 players online: bob, carol")
+	if !reflect.DeepEqual(panel.joined, []string{"carol"}) || !reflect.DeepEqual(panel.left, []string{"alice"}) {
+		t.Errorf("Unexpected changes %v %v", panel.joined, panel.left)
+	}
+	if len(m.history) != 0 {
+		t.Errorf("Polls should not reach the history")
+	}
+
+	// Answers of an old panel are ignored
+	model, _ = m.Update(playerListMsg{panel: &playerPanel{}, msg: commandOutputMsg{output: "There are 0 of a max of 20 players online:"}})
+	m = model.(commandModel)
+	if panel.online != 2 {
+		t.Errorf("Old panel changed the players")
+	}
+
+	// Expired session: the screen on top asks the password, the poll is replayed
+	model, cmd = m.Update(playerListMsg{panel: panel, msg: sessionExpiredMsg{title: "list"}})
+	m = model.(commandModel)
+	expired, ok := cmd().(sessionExpiredMsg)
+	if !ok || expired.replay == nil || panel.err != "session expired" {
+		t.Fatalf("Expected the expired session to be sent on, got %+v", panel)
+	}
+	model, _ = m.Update(expired)
+	m = model.(commandModel)
+	if m.reauth == nil || len(m.pending) != 1 {
+		t.Errorf("Expected the password prompt")
+	}
+	m.reauth, m.pending = nil, nil
+
+	// Select carol and ban her
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyDown}, {Type: tea.KeyEnter}, {Type: tea.KeyRunes, Runes: []rune("b")}} {
+		model, _ = m.Update(key)
+		m = model.(commandModel)
+	}
+	if value := m.commandInput.Value(); value != "ban carol " {
+		t.Errorf("Expected the prompt to be filled, got %q", value)
+	}
+	if panel.focused || panel.menuOpen {
+		t.Errorf("Expected the prompt to get the focus back")
+	}
+
+	m, _ = m.togglePlayers()
+	m, _ = m.togglePlayers()
+	if m.players != nil || m.viewport.Width != 100 {
+		t.Errorf("Expected the panel to be hidden")
+	}
+}
+
+func TestParsePlayerList(t *testing.T) {
+	tests := []struct {
+		input  string
+		online int
+		max    int
+		names  []string
+	}{
+		{
+			input:  "There are 2 of a max of 20 players online: alice, bob",
+			online: 2,
+			max:    20,
+			names:  []string{"alice", "bob"},
+		},
+		{
+			input:  "There are 0 of a max of 20 players online: ",
+			online: 0,
+			max:    20,
+		},
+		{
+			input:  "There are 3 out of maximum 50 players online.\nadmin: alice\ndefault: bob, carol",
+			online: 3,
+			max:    50,
+			names:  []string{"alice", "bob", "carol"},
+		},
+	}
+
+	for _, tc := range tests {
+		online, max, names := parsePlayerList(tc.input)
+		if online != tc.online || max != tc.max || !slices.Equal(names, tc.names) {
+			t.Errorf("%q: got %d %d %v", tc.input, online, max, names)
+		}
+	}
+}
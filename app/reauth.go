@@ -0,0 +1,243 @@
+package app
+
+import (
+	"fmt"
+	"log"
+
+	"mctui/colors"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// Send when the server rejects the token
+// The request is replayed after the user logs in again
+type sessionExpiredMsg struct {
+	// Command or task that was rejected
+	title string
+	// Tasks are replayed in the await screen
+	task   bool
+	replay func(jwtToken string) tea.Cmd
+}
+
+// Send to the screens below the current one, so they use the new token too
+type tokenRefreshedMsg struct {
+	jwtToken string
+}
+
+// Asks for the password again when the session expires
+// Drawn over the current screen, so the history is kept
+type reauthPrompt struct {
+	usernameInput textinput.Model
+	passwordInput textinput.Model
+	focusUsername bool
+	// Waiting for the server
+	sent bool
+	err  error
+}
+
+func newReauthPrompt(username string) *reauthPrompt {
+	ui := textinput.New()
+	ui.Placeholder = "username"
+	ui.CharLimit = 16
+	ui.Width = 8
+	ui.Prompt = "  "
+	ui.PlaceholderStyle = lipgloss.NewStyle().Foreground(colors.Surface1)
+	ui.PromptStyle = lipgloss.NewStyle().Foreground(colors.Pink)
+	ui.SetValue(username)
+
+	pi := textinput.New()
+	pi.Placeholder = "********"
+	pi.CharLimit = 16
+	pi.Width = 8
+	pi.Prompt = "  "
+	pi.EchoMode = textinput.EchoPassword
+	pi.PlaceholderStyle = lipgloss.NewStyle().Foreground(colors.Surface1)
+	pi.PromptStyle = lipgloss.NewStyle().Foreground(colors.Pink)
+
+	p := &reauthPrompt{
+		usernameInput: ui,
+		passwordInput: pi,
+	}
+	// Usually we know who was logged in
+	if username == "" {
+		p.focusUsername = true
+		p.usernameInput.Focus()
+	} else {
+		p.passwordInput.Focus()
+	}
+	return p
+}
+
+// Returns the command to authenticate when the user submits the form
+func (p *reauthPrompt) Update(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEnter:
+		username := p.usernameInput.Value()
+		password := p.passwordInput.Value()
+		if username == "" || password == "" || p.sent {
+			return nil
+		}
+		log.Printf("Login again as %s", username)
+		p.sent = true
+		p.err = nil
+		return func() tea.Msg {
+			return requestAuthenticateUser(username, password)
+		}
+	case tea.KeyTab:
+		p.focusUsername = !p.focusUsername
+		if p.focusUsername {
+			p.usernameInput.Focus()
+			p.passwordInput.Blur()
+		} else {
+			p.passwordInput.Focus()
+			p.usernameInput.Blur()
+		}
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if p.focusUsername {
+		p.usernameInput, cmd = p.usernameInput.Update(msg)
+	} else {
+		p.passwordInput, cmd = p.passwordInput.Update(msg)
+	}
+	return cmd
+}
+
+// Bad credentials or server down. Let the user try again
+func (p *reauthPrompt) Failed(err error) {
+	p.sent = false
+	p.err = err
+	p.passwordInput.SetValue("")
+}
+
+func (p *reauthPrompt) View(width, height int) string {
+	titleStyle := lipgloss.NewStyle().Foreground(colors.Pink).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(colors.Pink)
+	descStyle := lipgloss.NewStyle().Foreground(colors.Surface2)
+
+	title := titleStyle.Render("Session expired")
+	desc := descStyle.Render("Login again to continue")
+	if p.err != nil {
+		desc = lipgloss.NewStyle().Foreground(colors.Red).
+			Render(wordwrap.String(fmt.Sprintf("%v", p.err), max(width-12, 20)))
+	}
+	username := fmt.Sprintf("%s%s", labelStyle.Render("username"), p.usernameInput.View())
+	password := fmt.Sprintf("%s%s", labelStyle.Render("password"), p.passwordInput.View())
+	help := descStyle.Render("enter login • esc quit session")
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.Surface1).
+		Padding(1, 2).
+		Align(lipgloss.Center).
+		Render(lipgloss.JoinVertical(lipgloss.Center, title, desc, "", username, password, "", help))
+
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// Password prompt of the screens over the command one
+// The requests rejected meanwhile, e.g. background polls, are replayed after the login
+type screenReauth struct {
+	prompt  *reauthPrompt
+	pending []sessionExpiredMsg
+}
+
+func (r screenReauth) active() bool {
+	return r.prompt != nil
+}
+
+func (r *screenReauth) expired(msg sessionExpiredMsg, jwtToken string) {
+	log.Printf("Session expired running %s", msg.title)
+	if r.prompt == nil {
+		r.prompt = newReauthPrompt(storedUsername(jwtToken))
+		forgetToken(jwtToken)
+	}
+	r.pending = append(r.pending, msg)
+}
+
+// Returns the new token and the replays, or false if the login failed
+func (r *screenReauth) authenticated(msg authMsg) (string, tea.Cmd, bool) {
+	if !msg.sucess {
+		err := msg.err
+		if err == nil {
+			err = fmt.Errorf("bad credentials")
+		}
+		r.prompt.Failed(err)
+		return "", nil, false
+	}
+	saveToken(msg.username, msg.jwtToken)
+	var cmds []tea.Cmd
+	for _, p := range r.pending {
+		if p.replay != nil {
+			log.Printf("Replay %s", p.title)
+			cmds = append(cmds, p.replay(msg.jwtToken))
+		}
+	}
+	r.prompt = nil
+	r.pending = nil
+	return msg.jwtToken, tea.Batch(cmds...), true
+}
+
+// Handles the prompt keys, the expired sessions and the logins of a screen
+// ok is false for the messages the screen handles itself
+// next replaces the screen, e.g. the login when esc quits the session
+// prevModel and jwtToken get the new token after the login
+func (r *screenReauth) update(msg tea.Msg, prevModel *tea.Model, jwtToken *string) (next tea.Model, cmd tea.Cmd, ok bool) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if !r.active() {
+			return nil, nil, false
+		}
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return nil, tea.Quit, true
+		case tea.KeyEscape:
+			next, cmd := quitSession(*prevModel)
+			return next, cmd, true
+		}
+		return nil, r.prompt.Update(msg), true
+	case sessionExpiredMsg:
+		r.expired(msg, *jwtToken)
+		return nil, nil, true
+	case authMsg:
+		if !r.active() {
+			return nil, nil, true
+		}
+		token, replay, ok := r.authenticated(msg)
+		if !ok {
+			return nil, nil, true
+		}
+		*jwtToken = token
+		// The command screen must use the new token too
+		*prevModel, _ = (*prevModel).Update(tokenRefreshedMsg{jwtToken: token})
+		// Background polls may have been rejected too
+		return nil, replay, true
+	}
+	return nil, nil, false
+}
+
+// Returns the prompt while it's shown
+func (r screenReauth) view(width, height int) (string, bool) {
+	if !r.active() {
+		return "", false
+	}
+	return r.prompt.View(width, height), true
+}
+
+// esc on the prompt of a screen quits the session
+func quitSession(prevModel tea.Model) (tea.Model, tea.Cmd) {
+	if commandModel, ok := prevModel.(commandModel); ok {
+		return commandModel.backToLogin()
+	}
+	return prevModel, nil
+}
+
+// Sends a rejected background request to the screen on top,
+// which asks the password. The command screen may be below another one
+func expiredCmd(msg sessionExpiredMsg) tea.Cmd {
+	return func() tea.Msg { return msg }
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestScreenReauth(t *testing.T) {
+	setupFakeServer(t)
+	var prevModel tea.Model = InitialCommandModel(InitialLoginModel(), expiredToken, 80, 24)
+	jwtToken := expiredToken
+	var r screenReauth
+
+	// Keys and logins belong to the screen until the session expires
+	if _, _, ok := r.update(tea.KeyMsg{Type: tea.KeyEscape}, &prevModel, &jwtToken); ok {
+		t.Errorf("Expected the screen to handle the keys")
+	}
+	if _, cmd, ok := r.update(authMsg{sucess: true}, &prevModel, &jwtToken); !ok || cmd != nil {
+		t.Errorf("Expected stray logins to be dropped")
+	}
+
+	r.update(sessionExpiredMsg{title: "list", replay: func(string) tea.Cmd { return nil }}, &prevModel, &jwtToken)
+	if _, ok := r.view(80, 24); !ok {
+		t.Fatalf("Expected the password prompt")
+	}
+	if next, _, ok := r.update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")}, &prevModel, &jwtToken); !ok || next != nil {
+		t.Errorf("Expected the keys to go to the prompt")
+	}
+	if next, _, _ := r.update(tea.KeyMsg{Type: tea.KeyEscape}, &prevModel, &jwtToken); next == nil {
+		t.Errorf("Expected esc to quit the session")
+	} else if _, ok := next.(loginModel); !ok {
+		t.Errorf("Expected the login screen, got %T", next)
+	}
+
+	r.update(authMsg{username: "admin", jwtToken: testToken, sucess: true}, &prevModel, &jwtToken)
+	if r.active() || jwtToken != testToken || prevModel.(commandModel).jwtToken != testToken {
+		t.Errorf("Expected the new token on the screen and below it")
+	}
+}
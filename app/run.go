@@ -0,0 +1,140 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"mctui/colors"
+	"mctui/script"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Steps of a macro or a script
+type stepSource interface {
+	Name() string
+	Next() (script.Step, bool, error)
+	// Tells if the run goes on after a failed command
+	ContinueOnError() bool
+}
+
+// A macro or script running in the command screen
+// Shared by the copies of the model, so the steps move forward once
+type stepRun struct {
+	source stepSource
+	// Command waiting for its output
+	current string
+	// Question waiting for y/n
+	confirm string
+}
+
+// Send when a sleep step is over
+type runSleepMsg struct {
+	run *stepRun
+}
+
+// Loads the script for !run <path> [args]
+func loadScript(args []string) (*script.Runner, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("usage: !run <path> [args]")
+	}
+	path := expandHome(args[0])
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := script.Parse(path, f)
+	if err != nil {
+		return nil, err
+	}
+	return s.Runner(args[1:], nil), nil
+}
+
+func (m commandModel) startRun(source stepSource) (tea.Model, tea.Cmd) {
+	log.Printf("Start %s", source.Name())
+	m.run = &stepRun{source: source}
+	return m.nextStep()
+}
+
+func (m commandModel) nextStep() (tea.Model, tea.Cmd) {
+	run := m.run
+	step, ok, err := run.source.Next()
+	if err != nil {
+		return m.stopRun(err.Error()), nil
+	}
+	if !ok {
+		log.Printf("%s done", run.source.Name())
+		m.run = nil
+		return m, nil
+	}
+	run.current = ""
+
+	switch step.Kind {
+	case script.Sleep:
+		m = m.appendHistory(commandOutputMsg{command: step.String(), output: run.source.Name()})
+		return m, tea.Tick(step.Sleep, func(time.Time) tea.Msg {
+			return runSleepMsg{run: run}
+		})
+	case script.Confirm:
+		run.confirm = step.Prompt
+		return m, nil
+	}
+
+	run.current = step.Command
+	cmd := parseCommand(m, step.Command, m.jwtToken)
+	if isTask(step.Command) {
+		model, cmd := m.runTask(step.Command, cmd)
+		await := model.(modelAwait)
+		await.autoClose = true
+		return await, cmd
+	}
+	return m, cmd
+}
+
+// Moves the run forward when the output of its command arrives
+func (m commandModel) stepDone(entry commandOutputMsg) (tea.Model, tea.Cmd) {
+	if m.run == nil || m.run.current == "" || entry.command != m.run.current {
+		return m, nil
+	}
+	if entry.failed && !m.run.source.ContinueOnError() {
+		return m.stopRun(fmt.Sprintf("stopped, %s failed", entry.command)), nil
+	}
+	return m.nextStep()
+}
+
+func (m commandModel) stopRun(reason string) commandModel {
+	name := m.run.source.Name()
+	log.Printf("%s %s", name, reason)
+	m.run = nil
+	return m.appendHistory(commandOutputMsg{
+		command: name,
+		output:  reason,
+		failed:  true,
+	})
+}
+
+// Keys while a confirm waits for an answer
+func (m commandModel) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch strings.ToLower(msg.String()) {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "y":
+		m = m.appendHistory(commandOutputMsg{command: m.run.confirm, output: "yes"})
+		m.run.confirm = ""
+		return m.nextStep()
+	case "n", "esc":
+		m.run.confirm = ""
+		return m.stopRun("cancelled"), nil
+	}
+	return m, nil
+}
+
+func (m commandModel) confirmView() string {
+	style := lipgloss.NewStyle().MaxWidth(m.width).Foreground(colors.Pink).Bold(true)
+	return style.Render(fmt.Sprintf("%s (y/n)", m.run.confirm))
+}
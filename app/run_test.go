@@ -0,0 +1,35 @@
+package app
+
+import (
+	"testing"
+
+	"mctui/macro"
+)
+
+func TestStepRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 80, 24)
+
+	steps := []macro.Step{{Command: "say one"}, {Command: "say two"}, {Command: "say three"}}
+	model, _ := m.startRun(&macroSteps{name: "count", steps: steps})
+	if current := model.(commandModel).run.current; current != "say one" {
+		t.Fatalf("Expected say one, got %q", current)
+	}
+
+	// Output of other commands doesn't move the run
+	model, _ = model.Update(commandOutputMsg{command: "list"})
+	model, _ = model.Update(commandOutputMsg{command: "say one"})
+	if current := model.(commandModel).run.current; current != "say two" {
+		t.Fatalf("Expected say two, got %q", current)
+	}
+
+	// A failed step stops it
+	model, _ = model.Update(commandOutputMsg{command: "say two", failed: true})
+	m = model.(commandModel)
+	if m.run != nil {
+		t.Errorf("Expected the run to stop")
+	}
+	if last := m.history[len(m.history)-1]; last.command != "count" || !last.failed {
+		t.Errorf("Unexpected last entry %+v", last)
+	}
+}
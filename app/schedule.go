@@ -0,0 +1,442 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"mctui/client"
+	"mctui/colors"
+	"mctui/mcformat"
+	"mctui/schedule"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/dustin/go-humanize"
+)
+
+// Jobs of !schedule, kept while the client runs
+// Shared by the copies of the command model
+type scheduler struct {
+	jobs schedule.List
+	// Set while a tick is on the way, so there is a single loop
+	ticking bool
+}
+
+// Messages for the command screen that may arrive while another
+// screen is shown. The other screens forward them to prevModel
+type backgroundMsg interface {
+	background()
+}
+
+// Checks the jobs every second while there are some
+type scheduleTickMsg struct{}
+
+// Result of a job, wrapped so it doesn't look like something typed
+type scheduledResultMsg struct {
+	id      int
+	command string
+	msg     tea.Msg
+}
+
+func (scheduleTickMsg) background()    {}
+func (scheduledResultMsg) background() {}
+
+func scheduleTick() tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return scheduleTickMsg{}
+	})
+}
+
+// Handles !schedule [--server] in|every|cron ...
+// Without arguments it opens the schedules screen
+func (m commandModel) addSchedule(userCmd string) (tea.Model, tea.Cmd) {
+	m = m.remember(userCmd)
+	m.commandInput.SetValue("")
+	if m.schedules == nil {
+		m.schedules = &scheduler{}
+	}
+
+	text := strings.TrimSpace(strings.TrimPrefix(userCmd, "!schedule"))
+	if text == "" {
+		newModel := InitialScheduleModel(m, m.jwtToken, m.width, m.height)
+		return newModel, newModel.Init()
+	}
+	text, server := strings.CutPrefix(text, "--server ")
+
+	when, command, err := schedule.Parse(text, time.Now())
+	if err != nil {
+		m = m.appendHistory(commandOutputMsg{command: userCmd, output: err.Error(), failed: true})
+		return m, nil
+	}
+	if server {
+		return m, requestAddServerSchedule(userCmd, when.String(), command, m.jwtToken)
+	}
+
+	job := m.schedules.jobs.Add(when, command, time.Now())
+	log.Printf("Scheduled #%d %s %s", job.ID, when, command)
+	output := fmt.Sprintf("#%d %s, next run at %s", job.ID, when, job.Next.Format("15:04:05"))
+	m = m.appendHistory(commandOutputMsg{command: userCmd, output: output})
+	if m.schedules.ticking {
+		return m, nil
+	}
+	m.schedules.ticking = true
+	return m, scheduleTick()
+}
+
+// Sends the jobs due now, their results go to the history
+func (m commandModel) runDueJobs(now time.Time) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, job := range m.schedules.jobs.Due(now) {
+		log.Printf("Run scheduled #%d %s", job.ID, job.Command)
+		cmds = append(cmds, scheduled(job.ID, job.Command, parseCommand(m, job.Command, m.jwtToken)))
+	}
+	// Stop ticking until the next job is added
+	if m.schedules.jobs.Len() == 0 {
+		m.schedules.ticking = false
+	} else {
+		cmds = append(cmds, scheduleTick())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func scheduled(id int, command string, cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd()
+		// Replayed after the login, still in the background
+		if expired, ok := msg.(sessionExpiredMsg); ok {
+			replay := expired.replay
+			expired.task = false
+			expired.replay = func(jwtToken string) tea.Cmd {
+				return scheduled(id, command, replay(jwtToken))
+			}
+			msg = expired
+		}
+		return scheduledResultMsg{id: id, command: command, msg: msg}
+	}
+}
+
+func (m commandModel) scheduledResult(msg scheduledResultMsg) (tea.Model, tea.Cmd) {
+	// The screen on top asks the password
+	if expired, ok := msg.msg.(sessionExpiredMsg); ok {
+		return m, expiredCmd(expired)
+	}
+	entry, ok := historyEntry(msg.msg)
+	if !ok {
+		return m.Update(msg.msg)
+	}
+	entry.command = fmt.Sprintf("%s (schedule #%d)", msg.command, msg.id)
+	m.schedules.jobs.Finished(msg.id, mcformat.Strip(entry.output), entry.failed)
+	m = m.appendHistory(entry)
+	return m, nil
+}
+
+// ///////////////
+// Schedules screen
+// ///////////////
+
+// A local job or a server schedule
+type scheduleItem struct {
+	job    *schedule.Job
+	server *client.Schedule
+}
+
+func (i scheduleItem) Title() string {
+	if i.server != nil {
+		return i.server.Command
+	}
+	return fmt.Sprintf("#%d %s", i.job.ID, i.job.Command)
+}
+
+// e.g. every 30m0s • next 14:30:00 • 3 runs • last: Saved the game
+func (i scheduleItem) Description() string {
+	var parts []string
+	if i.server != nil {
+		parts = append(parts, "server", i.server.Spec)
+		parts = append(parts, nextRun(i.server.Next, i.server.Paused))
+		if i.server.Last != "" {
+			parts = append(parts, "last: "+i.server.Last)
+		}
+		return strings.Join(parts, " • ")
+	}
+
+	parts = append(parts, i.job.When.String(), nextRun(i.job.Next, i.job.Paused))
+	if i.job.Runs > 0 {
+		parts = append(parts, fmt.Sprintf("%d runs", i.job.Runs))
+	}
+	if i.job.Last != "" {
+		last := "last: " + strings.ReplaceAll(i.job.Last, "\n", " ")
+		if i.job.Failed {
+			last = "failed: " + strings.ReplaceAll(i.job.Last, "\n", " ")
+		}
+		parts = append(parts, last)
+	}
+	return strings.Join(parts, " • ")
+}
+
+func (i scheduleItem) FilterValue() string { return i.Title() }
+
+func nextRun(next time.Time, paused bool) string {
+	switch {
+	case paused:
+		return "paused"
+	case next.IsZero():
+		return "done"
+	default:
+		return fmt.Sprintf("next %s (%s)", next.Format("15:04:05"), humanize.Time(next))
+	}
+}
+
+var scheduleKeys = []key.Binding{
+	key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "pause/resume")),
+	key.NewBinding(key.WithKeys("x", "delete"), key.WithHelp("x", "cancel")),
+	key.NewBinding(key.WithKeys("r"), key.WithHelp("r", "refresh")),
+}
+
+// Lists the jobs of !schedule and the server schedules
+type scheduleModel struct {
+	list      list.Model
+	jwtToken  string
+	prevModel tea.Model
+	width     int
+	height    int
+	// nil when the server doesn't support schedules
+	server []client.Schedule
+	// Last error of a server request
+	err error
+	// Shown when the session expires
+	reauth screenReauth
+}
+
+// Send with the server schedules
+type serverSchedulesMsg struct {
+	schedules []client.Schedule
+	err       error
+}
+
+func InitialScheduleModel(prevModel commandModel, jwtToken string, width, height int) scheduleModel {
+	m := scheduleModel{
+		list:      list.New(nil, list.NewDefaultDelegate(), 0, 0),
+		prevModel: prevModel,
+		jwtToken:  jwtToken,
+		width:     width,
+		height:    height,
+	}
+	m.list.Title = "Schedules"
+	m.list.AdditionalShortHelpKeys = func() []key.Binding { return scheduleKeys }
+	m.list.AdditionalFullHelpKeys = func() []key.Binding { return scheduleKeys }
+	m.list.SetItems(m.items())
+	return m
+}
+
+func (m scheduleModel) Init() tea.Cmd {
+	return tea.Batch(
+		requestServerSchedules(m.jwtToken),
+		func() tea.Msg {
+			return tea.WindowSizeMsg{Width: m.width, Height: m.height}
+		},
+	)
+}
+
+func (m scheduleModel) jobs() *schedule.List {
+	return &m.prevModel.(commandModel).schedules.jobs
+}
+
+func (m scheduleModel) items() []list.Item {
+	var items []list.Item
+	for _, job := range m.jobs().Jobs() {
+		items = append(items, scheduleItem{job: job})
+	}
+	for _, s := range m.server {
+		items = append(items, scheduleItem{server: &s})
+	}
+	return items
+}
+
+func (m scheduleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// Ask the password here, the replies come back to this screen
+	if next, cmd, ok := m.reauth.update(msg, &m.prevModel, &m.jwtToken); ok {
+		if next == nil {
+			next = m
+		}
+		return next, cmd
+	}
+
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		// Keys belong to the filter input while typing
+		if m.list.FilterState() == list.Filtering {
+			break
+		}
+		item, selected := m.list.SelectedItem().(scheduleItem)
+		switch msg.String() {
+		case "esc":
+			if m.list.FilterState() == list.Unfiltered {
+				return m.prevModel.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+			}
+		case "r":
+			return m, requestServerSchedules(m.jwtToken)
+		case "p":
+			if !selected {
+				return m, nil
+			}
+			if item.server != nil {
+				return m, requestPauseServerSchedule(item.server.ID, !item.server.Paused, m.jwtToken)
+			}
+			m.jobs().SetPaused(item.job.ID, !item.job.Paused, time.Now())
+			return m, m.list.SetItems(m.items())
+		case "x", "delete":
+			if !selected {
+				return m, nil
+			}
+			if item.server != nil {
+				return m, requestDeleteServerSchedule(item.server.ID, m.jwtToken)
+			}
+			m.jobs().Remove(item.job.ID)
+			return m, m.list.SetItems(m.items())
+		}
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		h, v := docStyle.GetFrameSize()
+		m.list.SetSize(msg.Width-h, msg.Height-v-1)
+	case serverSchedulesMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.server = msg.schedules
+		}
+		return m, m.list.SetItems(m.items())
+	case backgroundMsg:
+		m.prevModel, cmd = m.prevModel.Update(msg)
+		// Times and results changed
+		return m, tea.Batch(cmd, m.list.SetItems(m.items()))
+	}
+
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m scheduleModel) View() string {
+	if view, ok := m.reauth.view(m.width, m.height); ok {
+		return view
+	}
+	status := lipgloss.NewStyle().Foreground(colors.Surface1)
+	var footer string
+	switch {
+	case m.err != nil:
+		footer = status.Foreground(colors.Red).Render(fmt.Sprintf("Server schedules: %v", m.err))
+	case m.server == nil:
+		footer = status.Render("Add jobs with !schedule in|every|cron ... They run while mctui is open")
+	default:
+		footer = status.Render("!schedule --server ... keeps running after mctui exits")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, docStyle.Render(m.list.View()), lipgloss.NewStyle().PaddingLeft(2).Render(footer))
+}
+
+// ///////////////
+// HTTP requests
+// ///////////////
+
+// Servers without schedules are not an error, there are just none
+func requestServerSchedules(jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		schedules, err := api.Schedules(jwtToken)
+		if client.IsNotFound(err) {
+			log.Printf("Server doesn't support schedules")
+			return serverSchedulesMsg{}
+		}
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!schedule",
+				replay: func(jwtToken string) tea.Cmd {
+					return requestServerSchedules(jwtToken)
+				},
+			}
+		}
+		if err != nil {
+			return serverSchedulesMsg{err: fmt.Errorf("%s", errorText(err))}
+		}
+		if schedules == nil {
+			schedules = []client.Schedule{}
+		}
+		return serverSchedulesMsg{schedules: schedules}
+	}
+}
+
+func requestPauseServerSchedule(id string, paused bool, jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		err := api.PauseSchedule(jwtToken, id, paused)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!schedule",
+				replay: func(jwtToken string) tea.Cmd {
+					return requestPauseServerSchedule(id, paused, jwtToken)
+				},
+			}
+		}
+		if err != nil {
+			return serverSchedulesMsg{err: fmt.Errorf("%s", errorText(err))}
+		}
+		return requestServerSchedules(jwtToken)()
+	}
+}
+
+func requestDeleteServerSchedule(id, jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		err := api.DeleteSchedule(jwtToken, id)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: "!schedule",
+				replay: func(jwtToken string) tea.Cmd {
+					return requestDeleteServerSchedule(id, jwtToken)
+				},
+			}
+		}
+		if err != nil {
+			return serverSchedulesMsg{err: fmt.Errorf("%s", errorText(err))}
+		}
+		return requestServerSchedules(jwtToken)()
+	}
+}
+
+func requestAddServerSchedule(title, spec, command, jwtToken string) tea.Cmd {
+	return func() tea.Msg {
+		sentAt := time.Now()
+		s, err := api.AddSchedule(jwtToken, spec, command)
+		rt := measure(sentAt, err)
+		if client.IsUnauthorized(err) {
+			return sessionExpiredMsg{
+				title: title,
+				replay: func(jwtToken string) tea.Cmd {
+					return requestAddServerSchedule(title, spec, command, jwtToken)
+				},
+			}
+		}
+		if isRequestError(err) {
+			return requestErrorMsg{
+				title:     title,
+				err:       err,
+				retry:     requestAddServerSchedule(title, spec, command, jwtToken),
+				roundTrip: rt,
+			}
+		}
+		if client.IsNotFound(err) {
+			return commandOutputMsg{command: title, output: "the server doesn't support schedules", failed: true, roundTrip: rt}
+		}
+		if err != nil {
+			return commandOutputMsg{command: title, output: errorText(err), failed: true, roundTrip: rt}
+		}
+		output := fmt.Sprintf("server schedule %s, %s", s.ID, s.Spec)
+		if !s.Next.IsZero() {
+			output += fmt.Sprintf(", next run at %s", s.Next.Local().Format("15:04:05"))
+		}
+		return commandOutputMsg{command: title, output: output, roundTrip: rt}
+	}
+}
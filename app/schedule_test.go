@@ -0,0 +1,98 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"mctui/client"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSchedule(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "", 80, 24)
+
+	model, _ := m.addSchedule("!schedule every soon say hi")
+	m = model.(commandModel)
+	if last := m.history[len(m.history)-1]; !last.failed || m.schedules.jobs.Len() != 0 {
+		t.Fatalf("Expected an error, got %+v", last)
+	}
+
+	model, cmd := m.addSchedule("!schedule every 10m say hi")
+	m = model.(commandModel)
+	if m.schedules.jobs.Len() != 1 || !m.schedules.ticking || cmd == nil {
+		t.Fatalf("Expected a job and a tick")
+	}
+
+	// Nothing to send yet, keeps ticking
+	model, cmd = m.runDueJobs(time.Now())
+	if cmd == nil {
+		t.Errorf("Expected the next tick")
+	}
+
+	model, _ = model.Update(scheduledResultMsg{id: 1, command: "say hi", msg: commandOutputMsg{command: "say hi", output: "§ehi"}})
+	m = model.(commandModel)
+	last := m.history[len(m.history)-1]
+	if last.command != "say hi (schedule #1)" || last.output != "§ehi" {
+		t.Errorf("Unexpected entry %+v", last)
+	}
+	if job := m.schedules.jobs.Get(1); job.Last != "hi" || job.Failed {
+		t.Errorf("Unexpected job %+v", job)
+	}
+
+	// Pause and cancel from the schedules screen
+	var screen tea.Model = InitialScheduleModel(m, "", 80, 24)
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+	if job := m.schedules.jobs.Get(1); !job.Paused {
+		t.Errorf("Expected a paused job")
+	}
+	screen, _ = screen.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if m.schedules.jobs.Len() != 0 {
+		t.Errorf("Expected no jobs")
+	}
+
+	model, _ = m.runDueJobs(time.Now())
+	if model.(commandModel).schedules.ticking {
+		t.Errorf("Expected the ticks to stop")
+	}
+}
+
+func TestScheduleReauth(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	m := InitialCommandModel(nil, "old", 80, 24)
+	var screen tea.Model = InitialScheduleModel(m, "old", 80, 24)
+
+	var replayedWith string
+	screen, _ = screen.Update(sessionExpiredMsg{
+		title: "!schedule",
+		replay: func(jwtToken string) tea.Cmd {
+			replayedWith = jwtToken
+			return func() tea.Msg {
+				return serverSchedulesMsg{schedules: []client.Schedule{{ID: "s1", Command: "save-all"}}}
+			}
+		},
+	})
+	if !screen.(scheduleModel).reauth.active() {
+		t.Fatalf("Expected the password prompt on the schedules screen")
+	}
+
+	screen, cmd := screen.Update(authMsg{username: "admin", jwtToken: "new", sucess: true})
+	s, ok := screen.(scheduleModel)
+	if !ok || s.reauth.active() || replayedWith != "new" {
+		t.Fatalf("Expected to stay on the screen and replay with the new token, got %T %q", screen, replayedWith)
+	}
+	if s.prevModel.(commandModel).jwtToken != "new" {
+		t.Errorf("Expected the command screen to get the new token")
+	}
+	screen, _ = screen.Update(cmd())
+	if len(screen.(scheduleModel).server) != 1 {
+		t.Errorf("Expected the replayed list to reach the screen")
+	}
+
+	// Jobs rejected in the background go to the screen on top
+	model, cmd := m.scheduledResult(scheduledResultMsg{id: 1, command: "say hi", msg: sessionExpiredMsg{title: "say hi"}})
+	if _, ok := cmd().(sessionExpiredMsg); !ok || model.(commandModel).reauth != nil {
+		t.Errorf("Expected the expired session to be sent on")
+	}
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"log"
+	"time"
+
+	"mctui/cli"
+	"mctui/session"
+)
+
+// Returns the token stored by a previous run, if it's still valid
+func storedToken() (string, bool) {
+	store, err := session.Default()
+	if err != nil {
+		log.Printf("Can't open sessions: %v", err)
+		return "", false
+	}
+	entry, ok, err := store.Load(cli.Args.Host, cli.Args.Port, cli.Args.Username)
+	if err != nil {
+		log.Printf("Can't load session: %v", err)
+		return "", false
+	}
+	if !ok || !session.Valid(entry.Token, time.Now()) {
+		return "", false
+	}
+	log.Printf("Using stored session of %s", entry.Username)
+	return entry.Token, true
+}
+
+// Removes every token for the server in cli.Args
+// If the username is given, only its token
+func forgetServerTokens() {
+	store, err := session.Default()
+	if err == nil {
+		err = store.Delete(cli.Args.Host, cli.Args.Port, cli.Args.Username)
+	}
+	if err != nil {
+		log.Printf("Can't remove sessions: %v", err)
+	}
+}
+
+// Keeps the token for the next runs
+func saveToken(username, token string) {
+	store, err := session.Default()
+	if err == nil {
+		err = store.Save(cli.Args.Host, cli.Args.Port, username, token)
+	}
+	if err != nil {
+		log.Printf("Can't save session: %v", err)
+	}
+}
+
+// Removes the stored token, so the next run asks for credentials
+func forgetToken(token string) {
+	store, err := session.Default()
+	if err == nil {
+		err = store.DeleteToken(token)
+	}
+	if err != nil {
+		log.Printf("Can't remove session: %v", err)
+	}
+}
+
+// Returns who logged in with the token, if we stored it
+func storedUsername(token string) string {
+	if cli.Args.Username != "" {
+		return cli.Args.Username
+	}
+	store, err := session.Default()
+	if err != nil {
+		return ""
+	}
+	entry, ok, err := store.Find(token)
+	if err != nil || !ok {
+		return ""
+	}
+	return entry.Username
+}
@@ -2,6 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"time"
+
+	"mctui/client"
+	"mctui/colors"
+	"mctui/config"
 )
 
 const (
@@ -11,22 +16,222 @@ const (
 
 var Args CliArgs
 
+const DEFAULT_HOST = "localhost"
+
 type CliArgs struct {
-	Host          string `short:"a" name:"host" default:"localhost" help:"Host"`
-	Port          int    `short:"p" name:"port" help:"Port" required:""`
-	TimeOffsetMin int    `short:"t" name:"time-offset" help:"Time offset used to diplay the backup time" default:"0"`
+	Config          string `name:"config" help:"Config file with server profiles. Defaults to config.toml in the config directory" type:"path"`
+	Profile         string `short:"P" name:"profile" help:"Server profile from the config file"`
+	Host            string `short:"a" name:"host" help:"Host (default: localhost)"`
+	Port            int    `short:"p" name:"port" help:"Port"`
+	Username        string `short:"u" name:"username" help:"Username. Also selects which stored session is used"`
+	Logout          bool   `name:"logout" help:"Forget the stored session for this server before starting"`
+	TimeOffsetMin   *int   `short:"t" name:"time-offset" help:"Time offset used to diplay the backup time"`
+	Theme           string `name:"theme" help:"Color theme: mocha, macchiato, frappe or latte"`
+	CACert          string `name:"ca-cert" help:"PEM file with certificate authorities trusted to sign the server certificate" type:"existingfile"`
+	PinSHA256       string `name:"pin-sha256" help:"Only accept the server certificate with this SHA-256 fingerprint"`
+	Insecure        *bool  `name:"insecure" help:"Don't verify the server certificate. Anyone on the network can read your password"`
+	ClientCert      string `name:"client-cert" help:"PEM file with a client certificate (mutual TLS)" type:"existingfile"`
+	ClientKey       string `name:"client-key" help:"PEM file with the client certificate key" type:"existingfile"`
+	ClientKeyPass   string `name:"client-key-passphrase" help:"Passphrase of an encrypted client key" env:"MCTUI_CLIENT_KEY_PASSPHRASE"`
+	NoHistory       *bool  `name:"no-history" help:"Don't save the commands you type"`
+	PlayersInterval int    `name:"players-interval" help:"Seconds between refreshes of the player panel (default: 10)"`
+
+	Tui    TuiCmd    `cmd:"" default:"1" help:"Open the terminal UI. Used when no command is given"`
+	Exec   ExecCmd   `cmd:"" help:"Run a RCON command or task and print its output"`
+	Backup BackupCmd `cmd:"" help:"Create, list and restore backups"`
+	Run    RunCmd    `cmd:"" help:"Run a script of commands and tasks"`
+
+	// No server given and many profiles in the config
+	// The user picks one before login
+	PickServer bool `kong:"-"`
+}
+
+type TuiCmd struct{}
+
+// Flags used when there is no one to type the password
+type LoginFlags struct {
+	Password string `name:"password" help:"Password used if there is no valid stored session" env:"MCTUI_PASSWORD"`
+}
+
+type ExecCmd struct {
+	LoginFlags
+	Command []string `arg:"" help:"Command to run, e.g. \"say hello\" or \"!backup\""`
+}
+
+type RunCmd struct {
+	LoginFlags
+	Script string            `arg:"" help:"Script file, see the README for the format" type:"existingfile"`
+	Args   []string          `arg:"" optional:"" help:"Arguments, used as $1 to $9"`
+	Vars   map[string]string `name:"var" help:"Set a variable, e.g. --var world=event"`
+	Yes    bool              `short:"y" name:"yes" help:"Answer yes to every confirm"`
+}
+
+type BackupCmd struct {
+	LoginFlags
+	Create  struct{}         `cmd:"" help:"Make a backup of the current save"`
+	List    BackupListCmd    `cmd:"" help:"List the backups, newest first"`
+	Restore BackupRestoreCmd `cmd:"" help:"Restore a backup"`
+}
+
+type BackupListCmd struct {
+	JSON bool `name:"json" help:"Print as JSON"`
+}
+
+type BackupRestoreCmd struct {
+	Filename string `arg:"" help:"Backup filename, or latest"`
+}
+
+// Fills the flags not given in the command line with the profile
+// from the config file. Flags always win
+// Returns the config file, so the profiles can be listed
+func (a *CliArgs) LoadProfile() (*config.File, error) {
+	path := a.Config
+	if path == "" {
+		var err error
+		path, err = config.DefaultPath()
+		if err != nil {
+			return nil, err
+		}
+	}
+	file, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// The default profile makes no sense if the server is in the flags
+	serverGiven := a.Host != "" || a.Port != 0
+	if a.Profile == "" && !serverGiven {
+		a.Profile = file.DefaultProfile
+	}
+	if a.Profile == "" && !serverGiven && len(file.Profiles) > 1 {
+		a.PickServer = true
+		return file, nil
+	}
+	if a.Profile == "" && !serverGiven && len(file.Profiles) == 1 {
+		a.Profile = file.ProfileNames()[0]
+	}
+
+	if a.Profile != "" {
+		p, err := file.Profile(a.Profile)
+		if err != nil {
+			return nil, err
+		}
+		a.UseProfile(a.Profile, p)
+	}
+	if a.Host == "" {
+		a.Host = DEFAULT_HOST
+	}
+	return file, nil
 }
 
-func (a CliArgs) Validate() error {
+// Selects the profile, e.g. from the server picker
+func (a *CliArgs) UseProfile(name string, p config.Profile) {
+	a.Profile = name
+	a.PickServer = false
+	a.ApplyProfile(p)
+	if a.Host == "" {
+		a.Host = DEFAULT_HOST
+	}
+}
+
+// Copies the profile values to the empty fields
+func (a *CliArgs) ApplyProfile(p config.Profile) {
+	setDefault(&a.Host, p.Host)
+	setDefault(&a.Port, p.Port)
+	setDefault(&a.Username, p.Username)
+	setDefault(&a.CACert, p.CACert)
+	setDefault(&a.PinSHA256, p.PinSHA256)
+	setDefaultPtr(&a.Insecure, p.Insecure)
+	setDefault(&a.ClientCert, p.ClientCert)
+	setDefault(&a.ClientKey, p.ClientKey)
+	setDefaultPtr(&a.TimeOffsetMin, p.TimeOffsetMin)
+	setDefault(&a.Theme, p.Theme)
+	setDefaultPtr(&a.NoHistory, p.NoHistory)
+	setDefault(&a.PlayersInterval, p.PlayersInterval)
+}
+
+func setDefault[T comparable](field *T, value T) {
+	var zero T
+	if *field == zero {
+		*field = value
+	}
+}
+
+// false and 0 are values too, e.g. --insecure=false overrides the profile
+func setDefaultPtr[T any](field **T, value T) {
+	if *field == nil {
+		*field = &value
+	}
+}
+
+func (a CliArgs) IsInsecure() bool {
+	return a.Insecure != nil && *a.Insecure
+}
+
+func (a CliArgs) IsNoHistory() bool {
+	return a.NoHistory != nil && *a.NoHistory
+}
+
+func (a CliArgs) TimeOffset() time.Duration {
+	if a.TimeOffsetMin == nil {
+		return 0
+	}
+	return time.Minute * time.Duration(*a.TimeOffsetMin)
+}
+
+// Not named Validate, kong would call it before the profile is applied
+func (a CliArgs) Check() error {
 	if a.Port == 0 {
 		return fmt.Errorf("you must specify a port")
 	}
 	if a.Port < PORT_MIN || a.Port > PORT_MAX {
 		return fmt.Errorf("port out of range")
 	}
+	if a.IsInsecure() && (a.CACert != "" || a.PinSHA256 != "") {
+		return fmt.Errorf("--insecure can't be used with --ca-cert or --pin-sha256")
+	}
+	if (a.ClientCert == "") != (a.ClientKey == "") {
+		return fmt.Errorf("--client-cert and --client-key must be used together")
+	}
+	if a.PlayersInterval < 0 {
+		return fmt.Errorf("--players-interval can't be negative")
+	}
+	if a.Theme != "" {
+		if _, ok := colors.Themes[a.Theme]; !ok {
+			return fmt.Errorf("unknown theme %q", a.Theme)
+		}
+	}
+	if a.PinSHA256 != "" {
+		if _, err := client.ParseFingerprint(a.PinSHA256); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Returns true if we present a client certificate
+// A proxy may authenticate us with it, so we don't need a password
+func (a CliArgs) HasClientCert() bool {
+	return a.ClientCert != ""
+}
+
 func (a CliArgs) Address(path string) string {
 	return fmt.Sprintf("https://%s:%d/%s", a.Host, a.Port, path)
 }
+
+// Creates the API client for the server in the args
+func (a CliArgs) Client() (*client.Client, error) {
+	knownHostsPath, err := config.Path("known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	return client.New(a.Address(""), client.WithTLS(client.TLSOptions{
+		CAFile:        a.CACert,
+		PinSHA256:     a.PinSHA256,
+		Insecure:      a.IsInsecure(),
+		KnownHosts:    client.OpenKnownHosts(knownHostsPath),
+		CertFile:      a.ClientCert,
+		KeyFile:       a.ClientKey,
+		KeyPassphrase: a.ClientKeyPass,
+	}))
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+const testConfig = `
+default_profile = "creative"
+
+[profiles.survival]
+host = "survival.example.com"
+port = 8090
+username = "admin"
+time_offset = -180
+theme = "latte"
+insecure = true
+no_history = true
+
+[profiles.creative]
+host = "creative.example.com"
+port = 8091
+`
+
+func writeConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := writeConfig(t)
+
+	// Flags override the profile
+	a := CliArgs{Config: path, Profile: "survival", Port: 9000}
+	if _, err := a.LoadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Host != "survival.example.com" || a.Port != 9000 || a.Username != "admin" ||
+		a.TimeOffset() != -3*time.Hour || a.Theme != "latte" {
+		t.Errorf("Unexpected args %+v", a)
+	}
+
+	if !a.IsInsecure() || !a.IsNoHistory() {
+		t.Errorf("Expected insecure and no history from the profile")
+	}
+
+	// false and 0 in the flags override the profile
+	a = CliArgs{}
+	if _, err := kong.Must(&a).Parse([]string{"--config=" + path, "--profile=survival", "--insecure=false", "--no-history=false", "--time-offset=0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.LoadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if a.IsInsecure() || a.IsNoHistory() || a.TimeOffset() != 0 {
+		t.Errorf("Expected the flags to win, got %v %v %v", *a.Insecure, *a.NoHistory, a.TimeOffset())
+	}
+
+	// Default profile
+	a = CliArgs{Config: path}
+	if _, err := a.LoadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Profile != "creative" || a.Host != "creative.example.com" || a.Port != 8091 {
+		t.Errorf("Unexpected args %+v", a)
+	}
+
+	// Server in the flags, don't use the default profile
+	a = CliArgs{Config: path, Port: 8090}
+	if _, err := a.LoadProfile(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Profile != "" || a.Host != DEFAULT_HOST {
+		t.Errorf("Unexpected args %+v", a)
+	}
+
+	// The port comes from the profile, after kong is done
+	a = CliArgs{}
+	if _, err := kong.Must(&a).Parse([]string{"--config=" + path, "--profile=survival"}); err != nil {
+		t.Fatal(err)
+	}
+
+	a = CliArgs{Config: path, Profile: "missing"}
+	if _, err := a.LoadProfile(); err == nil {
+		t.Errorf("Expected error for missing profile")
+	}
+}
+
+func TestPickServer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	os.WriteFile(path, []byte(`
+[profiles.survival]
+port = 8090
+[profiles.creative]
+port = 8091
+`), 0o600)
+
+	a := CliArgs{Config: path}
+	file, err := a.LoadProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.PickServer || len(file.Profiles) != 2 {
+		t.Errorf("Expected server picker, got %+v", a)
+	}
+
+	a.UseProfile("creative", file.Profiles["creative"])
+	if a.PickServer || a.Host != DEFAULT_HOST || a.Port != 8091 {
+		t.Errorf("Unexpected args %+v", a)
+	}
+}
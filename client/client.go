@@ -0,0 +1,314 @@
+// Package client talks to the mctui-server HTTP API
+//
+// It is used by the TUI but doesn't depend on it, so it can be used
+// to script against the same backend from Go
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Used for connection setup and the TLS handshake
+	dialTimeout = 5 * time.Second
+	// Login should be fast. Tasks like backups may take a long time
+	loginTimeout = 5 * time.Second
+)
+
+// Client holds a configured http.Client for a single server
+// It's safe for concurrent use
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	knownHosts *KnownHosts
+}
+
+type Option func(*Client) error
+
+// Use a custom TLS configuration for the connection
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Client) error {
+		c.transport().TLSClientConfig = config
+		return nil
+	}
+}
+
+// Limit the total time of each request
+// By default there is no limit, since tasks may take a while
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) error {
+		c.httpClient.Timeout = d
+		return nil
+	}
+}
+
+// Creates a client for the server at baseURL, e.g. https://localhost:8090
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout: dialTimeout,
+		}).DialContext,
+		TLSHandshakeTimeout: dialTimeout,
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Transport: transport},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *Client) transport() *http.Transport {
+	return c.httpClient.Transport.(*http.Transport)
+}
+
+// Returns the full url for an endpoint
+func (c *Client) URL(path string) string {
+	return fmt.Sprintf("%s/%s", c.baseURL, strings.TrimLeft(path, "/"))
+}
+
+// Server name used to verify its certificate
+func (c *Client) hostname() string {
+	return c.baseURL.Hostname()
+}
+
+// Identifies the server in the known hosts, e.g. localhost:8090
+func (c *Client) hostKey() string {
+	return c.baseURL.Host
+}
+
+// Returns a JWT token on success
+func (c *Client) Login(username, password string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	data := map[string]string{
+		"username": username,
+		"password": password,
+	}
+	body, err := c.do(ctx, http.MethodPost, "login", "", data)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Makes an authenticated request to check if the server accepts the token
+// With an empty token, checks if the connection itself is authenticated,
+// e.g. by a proxy that requires client certificates
+func (c *Client) CheckAuth(token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	_, err := c.do(ctx, http.MethodGet, "backups", token, nil)
+	return err
+}
+
+// Checks if the server is reachable: TLS handshake and login endpoint
+// Any HTTP answer counts, even bad credentials
+// Returns how long the request took
+func (c *Client) Probe() (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), loginTimeout)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.do(ctx, http.MethodPost, "login", "", map[string]string{})
+	if StatusCode(err) != 0 {
+		err = nil
+	}
+	return time.Since(start), err
+}
+
+// Runs a RCON command and returns its output
+func (c *Client) Command(token, command string) (string, error) {
+	data := map[string]string{"command": command}
+	body, err := c.do(context.Background(), http.MethodPost, "command", token, data)
+	return string(body), err
+}
+
+// Runs a task, e.g. "start" or "stop"
+// taskName should not include the ! prefix
+func (c *Client) Task(token, taskName string) (string, error) {
+	data := map[string]string{"task": taskName}
+	body, err := c.do(context.Background(), http.MethodPost, "task", token, data)
+	// Response may contain newlines or spaces
+	return strings.Trim(string(body), " \n"), err
+}
+
+// Makes a backup of the current save
+func (c *Client) Backup(token string) (string, error) {
+	body, err := c.do(context.Background(), http.MethodPost, "backup", token, nil)
+	return strings.TrimSpace(string(body)), err
+}
+
+// Lists the backup filenames available on the server
+func (c *Client) Backups(token string) ([]string, error) {
+	body, err := c.do(context.Background(), http.MethodGet, "backups", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	if err := json.Unmarshal(body, &filenames); err != nil {
+		return nil, &DecodeError{Path: "backups", Err: err}
+	}
+	return filenames, nil
+}
+
+// Restores the backup with the given filename
+func (c *Client) Restore(token, filename string) (string, error) {
+	data := map[string]string{"filename": filename}
+	body, err := c.do(context.Background(), http.MethodPost, "restore", token, data)
+	return strings.TrimSpace(string(body)), err
+}
+
+// A schedule kept by the server, so it runs after the client exits
+// Spec uses the syntax of !schedule, e.g. "every 30m" or "cron 0 4 * * *"
+type Schedule struct {
+	ID      string    `json:"id"`
+	Spec    string    `json:"spec"`
+	Command string    `json:"command"`
+	Next    time.Time `json:"next"`
+	Paused  bool      `json:"paused"`
+	// Output of the last run
+	Last string `json:"last"`
+}
+
+// Lists the server schedules
+// Servers without schedules answer 404, see IsNotFound
+func (c *Client) Schedules(token string) ([]Schedule, error) {
+	body, err := c.do(context.Background(), http.MethodGet, "schedules", token, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []Schedule
+	if err := json.Unmarshal(body, &schedules); err != nil {
+		return nil, &DecodeError{Path: "schedules", Err: err}
+	}
+	return schedules, nil
+}
+
+// Creates a schedule and returns it with its id
+func (c *Client) AddSchedule(token, spec, command string) (Schedule, error) {
+	data := map[string]string{"spec": spec, "command": command}
+	body, err := c.do(context.Background(), http.MethodPost, "schedules", token, data)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	var s Schedule
+	if err := json.Unmarshal(body, &s); err != nil {
+		return Schedule{}, &DecodeError{Path: "schedules", Err: err}
+	}
+	return s, nil
+}
+
+func (c *Client) PauseSchedule(token, id string, paused bool) error {
+	data := map[string]bool{"paused": paused}
+	_, err := c.do(context.Background(), http.MethodPatch, "schedules/"+url.PathEscape(id), token, data)
+	return err
+}
+
+func (c *Client) DeleteSchedule(token, id string) error {
+	_, err := c.do(context.Background(), http.MethodDelete, "schedules/"+url.PathEscape(id), token, nil)
+	return err
+}
+
+// Performance numbers of the server, nil when it doesn't report them
+type Metrics struct {
+	TPS  *float64 `json:"tps"`
+	MSPT *float64 `json:"mspt"`
+	// Bytes
+	HeapUsed     *int64 `json:"heap_used"`
+	HeapMax      *int64 `json:"heap_max"`
+	LoadedChunks *int   `json:"loaded_chunks"`
+}
+
+// Servers without metrics answer 404, see IsNotFound
+func (c *Client) Metrics(token string) (Metrics, error) {
+	body, err := c.do(context.Background(), http.MethodGet, "metrics", token, nil)
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	var m Metrics
+	if err := json.Unmarshal(body, &m); err != nil {
+		return Metrics{}, &DecodeError{Path: "metrics", Err: err}
+	}
+	return m, nil
+}
+
+// Makes the request and reads the whole body
+// The body is also returned when the status is not 200,
+// so callers can display the server message
+func (c *Client) do(ctx context.Context, method, path, token string, data any) ([]byte, error) {
+	req, err := c.newRequest(ctx, method, path, token, data)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := req.URL.String()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &RequestError{Method: method, URL: endpoint, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &RequestError{Method: method, URL: endpoint, Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return body, &StatusError{
+			Method:     method,
+			URL:        endpoint,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return body, nil
+}
+
+// JSON request with the token, if any
+func (c *Client) newRequest(ctx context.Context, method, path, token string, data any) (*http.Request, error) {
+	var reqBody io.Reader = http.NoBody
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("can't encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.URL(path), reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("can't create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	return req, nil
+}
@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *Client) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]string
+		json.NewDecoder(r.Body).Decode(&data)
+		if data["password"] != "secret" {
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("token123\n"))
+	})
+	mux.HandleFunc("POST /command", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token123" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var data map[string]string
+		json.NewDecoder(r.Body).Decode(&data)
+		w.Write([]byte("ran " + data["command"]))
+	})
+	mux.HandleFunc("GET /backups", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["backup-2024-01-02-03-04-05.zip"]`))
+	})
+
+	schedules := map[string]*Schedule{}
+	mux.HandleFunc("GET /schedules", func(w http.ResponseWriter, r *http.Request) {
+		list := []Schedule{}
+		for _, s := range schedules {
+			list = append(list, *s)
+		}
+		json.NewEncoder(w).Encode(list)
+	})
+	mux.HandleFunc("POST /schedules", func(w http.ResponseWriter, r *http.Request) {
+		var s Schedule
+		json.NewDecoder(r.Body).Decode(&s)
+		s.ID = "s1"
+		schedules[s.ID] = &s
+		json.NewEncoder(w).Encode(s)
+	})
+	mux.HandleFunc("PATCH /schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s, ok := schedules[r.PathValue("id")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(s)
+	})
+	mux.HandleFunc("DELETE /schedules/{id}", func(w http.ResponseWriter, r *http.Request) {
+		delete(schedules, r.PathValue("id"))
+	})
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tps": 19.5, "heap_used": 1073741824, "heap_max": 4294967296}`))
+	})
+
+	ts := httptest.NewTLSServer(mux)
+	t.Cleanup(ts.Close)
+	tlsConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig
+	c, err := New(ts.URL, WithTLSConfig(tlsConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts, c
+}
+
+func TestClient(t *testing.T) {
+	_, c := newTestServer(t)
+
+	token, err := c.Login("admin", "secret")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if token != "token123" {
+		t.Errorf("Expected token123, got %q", token)
+	}
+
+	output, err := c.Command(token, "list")
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if output != "ran list" {
+		t.Errorf("Expected %q, got %q", "ran list", output)
+	}
+
+	backups, err := c.Backups(token)
+	if err != nil {
+		t.Fatalf("backups: %v", err)
+	}
+	if len(backups) != 1 || backups[0] != "backup-2024-01-02-03-04-05.zip" {
+		t.Errorf("Unexpected backups %v", backups)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ts, c := newTestServer(t)
+
+	_, err := c.Login("admin", "wrong")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected StatusError, got %v", err)
+	}
+	if statusErr.StatusCode != http.StatusUnauthorized || statusErr.Body != "invalid credentials" {
+		t.Errorf("Unexpected error %+v", statusErr)
+	}
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected unauthorized error")
+	}
+
+	ts.Close()
+	_, err = c.Command("token123", "list")
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("Expected RequestError, got %v", err)
+	}
+	if StatusCode(err) != 0 {
+		t.Errorf("Expected no status code, got %d", StatusCode(err))
+	}
+}
+
+func TestSchedules(t *testing.T) {
+	_, c := newTestServer(t)
+
+	s, err := c.AddSchedule("token123", "every 30m", "save-all")
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if s.ID != "s1" || s.Spec != "every 30m" || s.Command != "save-all" {
+		t.Errorf("Unexpected schedule %+v", s)
+	}
+
+	if err := c.PauseSchedule("token123", s.ID, true); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	schedules, err := c.Schedules("token123")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(schedules) != 1 || !schedules[0].Paused {
+		t.Errorf("Expected a paused schedule, got %+v", schedules)
+	}
+
+	if err := c.DeleteSchedule("token123", s.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := c.PauseSchedule("token123", s.ID, false); !IsNotFound(err) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	_, c := newTestServer(t)
+
+	m, err := c.Metrics("token123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.TPS == nil || *m.TPS != 19.5 || m.HeapMax == nil || *m.HeapMax != 4<<30 {
+		t.Errorf("Unexpected metrics %+v", m)
+	}
+	if m.MSPT != nil || m.LoadedChunks != nil {
+		t.Errorf("Expected nil for the missing numbers, got %+v", m)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		lines       []string
+	}{
+		{
+			contentType: "text/event-stream",
+			body:        ": keepalive\nevent: log\ndata: [12:00:00] [Server thread/INFO]: Done\n\ndata:no space\r\n\n",
+			lines:       []string{"[12:00:00] [Server thread/INFO]: Done", "no space"},
+		},
+		{
+			contentType: "text/plain; charset=utf-8",
+			body:        "first\r\n\nthird\n",
+			lines:       []string{"first", "", "third"},
+		},
+	}
+
+	for _, tc := range tests {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/logs" || r.URL.Query().Get("follow") != "1" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", tc.contentType)
+			w.Write([]byte(tc.body))
+		}))
+		tlsConfig := ts.Client().Transport.(*http.Transport).TLSClientConfig
+		c, err := New(ts.URL, WithTLSConfig(tlsConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stream, err := c.Logs(context.Background(), "token123")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.contentType, err)
+		}
+		var lines []string
+		for {
+			line, err := stream.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tc.contentType, err)
+			}
+			lines = append(lines, line)
+		}
+		stream.Close()
+		ts.Close()
+		if !slices.Equal(lines, tc.lines) {
+			t.Errorf("%s: got %q", tc.contentType, lines)
+		}
+	}
+
+	_, c := newTestServer(t)
+	if _, err := c.Logs(context.Background(), "token123"); !IsNotFound(err) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
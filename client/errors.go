@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// The request didn't reach the server or the connection failed
+// e.g. server down, timeout, TLS errors
+type RequestError struct {
+	Method string
+	URL    string
+	Err    error
+}
+
+func (e *RequestError) Error() string {
+	if e.Timeout() {
+		return fmt.Sprintf("timeout error: %s %s: %v", e.Method, e.URL, e.Err)
+	}
+	return fmt.Sprintf("error making request: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+func (e *RequestError) Timeout() bool {
+	return os.IsTimeout(e.Err)
+}
+
+// The server answered with a status other than 200
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Server message, without leading and trailing spaces
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// The server answered 200, but the body is not what we expect
+type DecodeError struct {
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("can't decode %s response: %v", e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// The server certificate is not trusted yet
+// The user may accept it with Client.Trust
+type UnknownCertError struct {
+	Host        string
+	Fingerprint string
+}
+
+func (e *UnknownCertError) Error() string {
+	return fmt.Sprintf("unknown certificate for %s: SHA-256 %s", e.Host, FormatFingerprint(e.Fingerprint))
+}
+
+// The server certificate is not the one accepted before
+// Someone may be intercepting the connection
+type CertChangedError struct {
+	Host        string
+	Known       string
+	Fingerprint string
+}
+
+func (e *CertChangedError) Error() string {
+	return fmt.Sprintf("certificate for %s changed: expected SHA-256 %s, got %s",
+		e.Host, FormatFingerprint(e.Known), FormatFingerprint(e.Fingerprint))
+}
+
+// The server certificate doesn't match the pinned fingerprint
+type PinMismatchError struct {
+	Host        string
+	Pin         string
+	Fingerprint string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("certificate for %s doesn't match the pin: expected SHA-256 %s, got %s",
+		e.Host, FormatFingerprint(e.Pin), FormatFingerprint(e.Fingerprint))
+}
+
+// Returns the status code of a StatusError, or 0 if err is not one
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// Returns true if the server rejected the token, e.g. expired session
+func IsUnauthorized(err error) bool {
+	code := StatusCode(err)
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+// Returns true if the server doesn't have the endpoint, e.g. an older
+// mctui-server without schedules
+func IsNotFound(err error) bool {
+	code := StatusCode(err)
+	return code == http.StatusNotFound || code == http.StatusMethodNotAllowed
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// File with the certificates accepted by the user
+// One server per line: host:port fingerprint
+type KnownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+func OpenKnownHosts(path string) *KnownHosts {
+	return &KnownHosts{path: path}
+}
+
+// Returns the fingerprint stored for host, if any
+func (k *KnownHosts) Lookup(host string) (string, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.read()
+	if err != nil {
+		return "", false, err
+	}
+	fingerprint, ok := entries[host]
+	return fingerprint, ok, nil
+}
+
+// Stores the fingerprint for host, replacing the previous one
+func (k *KnownHosts) Add(host, fingerprint string) error {
+	fingerprint, err := ParseFingerprint(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.read()
+	if err != nil {
+		return err
+	}
+	entries[host] = fingerprint
+
+	var b strings.Builder
+	for _, h := range slices.Sorted(maps.Keys(entries)) {
+		fmt.Fprintf(&b, "%s %s\n", h, entries[h])
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return fmt.Errorf("can't create known hosts directory: %w", err)
+	}
+	if err := os.WriteFile(k.path, []byte(b.String()), 0o600); err != nil {
+		return fmt.Errorf("can't write known hosts: %w", err)
+	}
+	return nil
+}
+
+func (k *KnownHosts) read() (map[string]string, error) {
+	entries := make(map[string]string)
+
+	f, err := os.Open(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read known hosts: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		fingerprint, err := ParseFingerprint(fields[1])
+		if err != nil {
+			continue
+		}
+		entries[fields[0]] = fingerprint
+	}
+	return entries, scanner.Err()
+}
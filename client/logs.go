@@ -0,0 +1,84 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Longest console line, stack traces can be long
+const maxLogLine = 1 << 20
+
+// Server console, read line by line with Next
+type LogStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	// Server-sent events, only the data lines are console lines
+	events bool
+}
+
+// Follows the server console with GET /logs?follow=1
+// The server may answer with server-sent events or plain lines in a
+// chunked response. Returns once the server accepted the request
+// Servers without logs answer 404, see IsNotFound
+func (c *Client) Logs(ctx context.Context, token string) (*LogStream, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "logs?follow=1", token, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream, text/plain")
+	endpoint := req.URL.String()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &RequestError{Method: http.MethodGet, URL: endpoint, Err: err}
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &StatusError{
+			Method:     http.MethodGet,
+			URL:        endpoint,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxLogLine)
+	return &LogStream{
+		body:    resp.Body,
+		scanner: scanner,
+		events:  mediaType == "text/event-stream",
+	}, nil
+}
+
+// Blocks until the next line arrives
+// Returns io.EOF when the server closes the stream
+func (s *LogStream) Next() (string, error) {
+	for s.scanner.Scan() {
+		line := strings.TrimSuffix(s.scanner.Text(), "\r")
+		if !s.events {
+			return line, nil
+		}
+		// Comments, event names and ids are not console lines
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		return strings.TrimPrefix(data, " "), nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Stops following, Next returns an error afterwards
+func (s *LogStream) Close() error {
+	return s.body.Close()
+}
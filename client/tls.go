@@ -0,0 +1,212 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// How the server certificate is verified
+// With the zero value, only certificates signed by the system roots are accepted
+type TLSOptions struct {
+	// PEM file with certificate authorities trusted besides the system roots
+	CAFile string
+	// SHA-256 fingerprint of the server certificate
+	// When set, only this certificate is accepted
+	PinSHA256 string
+	// Don't verify the server certificate at all
+	Insecure bool
+	// Certificates the user accepted before (trust on first use)
+	// Unknown certificates fail with UnknownCertError
+	KnownHosts *KnownHosts
+	// PEM files with a client certificate and its key (mutual TLS)
+	CertFile string
+	KeyFile  string
+	// Used only if the key is encrypted
+	KeyPassphrase string
+}
+
+// Verify the server certificate and present a client certificate using opts
+func WithTLS(opts TLSOptions) Option {
+	return func(c *Client) error {
+		config, err := c.tlsConfig(opts)
+		if err != nil {
+			return err
+		}
+		c.transport().TLSClientConfig = config
+		c.knownHosts = opts.KnownHosts
+		return nil
+	}
+}
+
+func (c *Client) tlsConfig(opts TLSOptions) (*tls.Config, error) {
+	var certificates []tls.Certificate
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := LoadClientCertificate(opts.CertFile, opts.KeyFile, opts.KeyPassphrase)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, cert)
+	}
+
+	if opts.Insecure {
+		return &tls.Config{InsecureSkipVerify: true, Certificates: certificates}, nil
+	}
+
+	var pin string
+	if opts.PinSHA256 != "" {
+		var err error
+		pin, err = ParseFingerprint(opts.PinSHA256)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA file: %w", err)
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+	}
+
+	hostname := c.hostname()
+	hostKey := c.hostKey()
+	return &tls.Config{
+		Certificates: certificates,
+		// The default verification can't handle pinning and
+		// self-signed certificates, so we do it ourselves
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("server didn't send a certificate")
+			}
+			leaf := cs.PeerCertificates[0]
+			fingerprint := Fingerprint(leaf)
+
+			if pin != "" {
+				if fingerprint != pin {
+					return &PinMismatchError{Host: hostKey, Pin: pin, Fingerprint: fingerprint}
+				}
+				return nil
+			}
+
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, verifyErr := leaf.Verify(x509.VerifyOptions{
+				DNSName:       hostname,
+				Roots:         roots,
+				Intermediates: intermediates,
+			})
+			if verifyErr == nil {
+				return nil
+			}
+
+			if opts.KnownHosts == nil {
+				return verifyErr
+			}
+			known, ok, err := opts.KnownHosts.Lookup(hostKey)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return &UnknownCertError{Host: hostKey, Fingerprint: fingerprint}
+			}
+			if known != fingerprint {
+				return &CertChangedError{Host: hostKey, Known: known, Fingerprint: fingerprint}
+			}
+			return nil
+		},
+	}, nil
+}
+
+// Loads a PEM certificate and key
+// Legacy encrypted keys (Proc-Type: 4,ENCRYPTED) are decrypted with passphrase
+func LoadClientCertificate(certFile, keyFile, passphrase string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, fmt.Errorf("client certificate and key must be used together")
+	}
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("can't read client certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("can't read client key: %w", err)
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return tls.Certificate{}, fmt.Errorf("no PEM data found in %s", keyFile)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		return tls.Certificate{}, fmt.Errorf("encrypted PKCS#8 keys are not supported, convert %s with openssl", keyFile)
+	}
+	// Deprecated, but it's the only PEM encryption supported by the standard library
+	if x509.IsEncryptedPEMBlock(block) {
+		if passphrase == "" {
+			return tls.Certificate{}, fmt.Errorf("client key is encrypted, a passphrase is required")
+		}
+		der, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("can't decrypt client key: %w", err)
+		}
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// Accepts the certificate with the given fingerprint for this server
+// Following connections fail if the certificate changes
+func (c *Client) Trust(fingerprint string) error {
+	if c.knownHosts == nil {
+		return fmt.Errorf("no known hosts file configured")
+	}
+	return c.knownHosts.Add(c.hostKey(), fingerprint)
+}
+
+// Returns the lowercase hex SHA-256 of the certificate
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Accepts fingerprints with or without colons, in any case
+// e.g. AB:CD:... or abcd...
+func ParseFingerprint(s string) (string, error) {
+	fingerprint := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(s), ":", ""))
+	fingerprint = strings.TrimPrefix(fingerprint, "sha256/")
+	b, err := hex.DecodeString(fingerprint)
+	if err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid SHA-256 fingerprint: %s", s)
+	}
+	return fingerprint, nil
+}
+
+// Formats the fingerprint for humans, e.g. AB:CD:EF
+func FormatFingerprint(fingerprint string) string {
+	var pairs []string
+	upper := strings.ToUpper(fingerprint)
+	for i := 0; i+1 < len(upper); i += 2 {
+		pairs = append(pairs, upper[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTLSServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("token123"))
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestTrustOnFirstUse(t *testing.T) {
+	ts := newTLSServer(t)
+	knownHosts := OpenKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	c, err := New(ts.URL, WithTLS(TLSOptions{KnownHosts: knownHosts}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Login("admin", "secret")
+	var unknownCert *UnknownCertError
+	if !errors.As(err, &unknownCert) {
+		t.Fatalf("Expected UnknownCertError, got %v", err)
+	}
+	if unknownCert.Fingerprint != Fingerprint(ts.Certificate()) {
+		t.Errorf("Expected fingerprint %s, got %s", Fingerprint(ts.Certificate()), unknownCert.Fingerprint)
+	}
+
+	if err := c.Trust(unknownCert.Fingerprint); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Login("admin", "secret"); err != nil {
+		t.Fatalf("Expected trusted certificate, got %v", err)
+	}
+
+	// Someone else answers on the same address
+	knownHosts.Add(unknownCert.Host, strings.Repeat("00", 32))
+	c, _ = New(ts.URL, WithTLS(TLSOptions{KnownHosts: knownHosts}))
+	_, err = c.Login("admin", "secret")
+	var changed *CertChangedError
+	if !errors.As(err, &changed) {
+		t.Fatalf("Expected CertChangedError, got %v", err)
+	}
+}
+
+func TestPinAndCA(t *testing.T) {
+	ts := newTLSServer(t)
+
+	c, _ := New(ts.URL, WithTLS(TLSOptions{PinSHA256: FormatFingerprint(Fingerprint(ts.Certificate()))}))
+	if _, err := c.Login("admin", "secret"); err != nil {
+		t.Errorf("Expected pinned certificate, got %v", err)
+	}
+
+	c, _ = New(ts.URL, WithTLS(TLSOptions{PinSHA256: strings.Repeat("00", 32)}))
+	_, err := c.Login("admin", "secret")
+	var mismatch *PinMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("Expected PinMismatchError, got %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	os.WriteFile(caFile, caPEM, 0o600)
+	c, err = New(ts.URL, WithTLS(TLSOptions{CAFile: caFile}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Login("admin", "secret"); err != nil {
+		t.Errorf("Expected certificate signed by CA, got %v", err)
+	}
+}
+
+// Writes a self-signed client certificate and its key encrypted with passphrase
+func writeClientCertificate(t *testing.T, passphrase string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBlock, err := x509.EncryptPEMBlock(rand.Reader, "EC PRIVATE KEY", keyDER, []byte(passphrase), x509.PEMCipherAES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(keyBlock), 0o600)
+	return certFile, keyFile
+}
+
+func TestClientCertificate(t *testing.T) {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			http.Error(w, "no client certificate", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+
+	certFile, keyFile := writeClientCertificate(t, "hunter2")
+	pin := Fingerprint(ts.Certificate())
+
+	_, err := New(ts.URL, WithTLS(TLSOptions{PinSHA256: pin, CertFile: certFile, KeyFile: keyFile}))
+	if err == nil {
+		t.Errorf("Expected error without passphrase")
+	}
+	_, err = New(ts.URL, WithTLS(TLSOptions{PinSHA256: pin, CertFile: certFile, KeyFile: keyFile, KeyPassphrase: "wrong"}))
+	if err == nil {
+		t.Errorf("Expected error with wrong passphrase")
+	}
+
+	c, err := New(ts.URL, WithTLS(TLSOptions{PinSHA256: pin, CertFile: certFile, KeyFile: keyFile, KeyPassphrase: "hunter2"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckAuth(""); err != nil {
+		t.Errorf("Expected authentication by certificate, got %v", err)
+	}
+}
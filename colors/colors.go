@@ -1,6 +1,10 @@
 package colors
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"fmt"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 var (
 	Surface0 = lipgloss.Color("#313244")
@@ -8,4 +12,62 @@ var (
 	Surface2 = lipgloss.Color("#585b70")
 	Pink     = lipgloss.Color("#f5c2e7")
 	Text     = lipgloss.Color("#cdd6f4")
+	Red      = lipgloss.Color("#f38ba8")
+	Green    = lipgloss.Color("#a6e3a1")
+	// Player names in the chat
+	Accents = Themes["mocha"].Accents
 )
+
+type Theme struct {
+	Surface0 lipgloss.Color
+	Surface1 lipgloss.Color
+	Surface2 lipgloss.Color
+	Pink     lipgloss.Color
+	Text     lipgloss.Color
+	Red      lipgloss.Color
+	Green    lipgloss.Color
+	// Mauve, peach, yellow, teal, sky, blue, lavender and flamingo
+	Accents []lipgloss.Color
+}
+
+// Catppuccin flavors. Mocha is the default
+var Themes = map[string]Theme{
+	"mocha": {
+		Surface0: "#313244", Surface1: "#45475a", Surface2: "#585b70",
+		Pink: "#f5c2e7", Text: "#cdd6f4", Red: "#f38ba8", Green: "#a6e3a1",
+		Accents: []lipgloss.Color{"#cba6f7", "#fab387", "#f9e2af", "#94e2d5", "#89dceb", "#89b4fa", "#b4befe", "#f2cdcd"},
+	},
+	"macchiato": {
+		Surface0: "#363a4f", Surface1: "#494d64", Surface2: "#5b6078",
+		Pink: "#f5bde6", Text: "#cad3f5", Red: "#ed8796", Green: "#a6da95",
+		Accents: []lipgloss.Color{"#c6a0f6", "#f5a97f", "#eed49f", "#8bd5ca", "#91d7e3", "#8aadf4", "#b7bdf8", "#f0c6c6"},
+	},
+	"frappe": {
+		Surface0: "#414559", Surface1: "#51576d", Surface2: "#626880",
+		Pink: "#f4b8e4", Text: "#c6d0f5", Red: "#e78284", Green: "#a6d189",
+		Accents: []lipgloss.Color{"#ca9ee6", "#ef9f76", "#e5c890", "#81c8be", "#99d1db", "#8caaee", "#babbf1", "#eebebe"},
+	},
+	"latte": {
+		Surface0: "#ccd0da", Surface1: "#bcc0cc", Surface2: "#acb0be",
+		Pink: "#ea76cb", Text: "#4c4f69", Red: "#d20f39", Green: "#40a02b",
+		Accents: []lipgloss.Color{"#8839ef", "#fe640b", "#df8e1d", "#179299", "#04a5e5", "#1e66f5", "#7287fd", "#dd7878"},
+	},
+}
+
+// Must be called before the program starts
+// Styles are created with the colors of the moment
+func SetTheme(name string) error {
+	t, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	Surface0 = t.Surface0
+	Surface1 = t.Surface1
+	Surface2 = t.Surface2
+	Pink = t.Pink
+	Text = t.Text
+	Red = t.Red
+	Green = t.Green
+	Accents = t.Accents
+	return nil
+}
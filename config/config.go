@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// A server the user connects to
+// Empty fields are left to the command line flags
+type Profile struct {
+	Host            string `toml:"host"`
+	Port            int    `toml:"port"`
+	Username        string `toml:"username"`
+	CACert          string `toml:"ca_cert"`
+	PinSHA256       string `toml:"pin_sha256"`
+	Insecure        bool   `toml:"insecure"`
+	ClientCert      string `toml:"client_cert"`
+	ClientKey       string `toml:"client_key"`
+	TimeOffsetMin   int    `toml:"time_offset"`
+	Theme           string `toml:"theme"`
+	NoHistory       bool   `toml:"no_history"`
+	PlayersInterval int    `toml:"players_interval"`
+}
+
+// Contents of config.toml
+//
+//	default_profile = "survival"
+//
+//	[profiles.survival]
+//	host = "mc.example.com"
+//	port = 8090
+type File struct {
+	// Used when no profile is given
+	DefaultProfile string             `toml:"default_profile"`
+	Profiles       map[string]Profile `toml:"profiles"`
+	// Shared by all the profiles, see package macro
+	Aliases map[string]string   `toml:"aliases"`
+	Macros  map[string][]string `toml:"macros"`
+	// Shared by all the profiles too
+	Dashboard Dashboard `toml:"dashboard"`
+}
+
+// Settings of the dashboard, zero values use the defaults
+//
+//	[dashboard]
+//	interval = 10
+//	min_tps = 18
+type Dashboard struct {
+	// Seconds between polls
+	Interval int `toml:"interval"`
+	// metrics, tps, forge or debug. Detected when empty
+	Source string `toml:"source"`
+	// Numbers past these are red
+	MinTPS  float64 `toml:"min_tps"`
+	MaxMSPT float64 `toml:"max_mspt"`
+	// Percent of the max heap
+	MaxHeap   float64 `toml:"max_heap"`
+	MaxChunks float64 `toml:"max_chunks"`
+}
+
+// Returns the path of config.toml in the config directory
+func DefaultPath() (string, error) {
+	return Path("config.toml")
+}
+
+// Reads the config file
+// A missing file is the same as an empty one
+func Load(path string) (*File, error) {
+	f := &File{}
+	_, err := toml.DecodeFile(path, f)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read config %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Returns the profile with this name
+func (f *File) Profile(name string) (Profile, error) {
+	p, ok := f.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("profile %q not found in config", name)
+	}
+	return p, nil
+}
+
+// Returns the profile names in alphabetical order
+func (f *File) ProfileNames() []string {
+	return slices.Sorted(maps.Keys(f.Profiles))
+}
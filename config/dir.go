@@ -0,0 +1,31 @@
+// Package config locates the files mctui keeps between sessions
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Returns the mctui config directory, e.g. ~/.config/mctui
+// It's created if it doesn't exist
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("can't find config directory: %w", err)
+	}
+	dir := filepath.Join(base, "mctui")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("can't create config directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Returns the path of a file inside the config directory
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
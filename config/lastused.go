@@ -0,0 +1,49 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// When each profile was used for the last time
+// Stored in last_used.json in the config directory
+func LastUsed() (map[string]time.Time, error) {
+	path, err := Path("last_used.json")
+	if err != nil {
+		return nil, err
+	}
+	lastUsed := make(map[string]time.Time)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return lastUsed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read last used profiles: %w", err)
+	}
+	if err := json.Unmarshal(data, &lastUsed); err != nil {
+		return nil, fmt.Errorf("can't parse last used profiles: %w", err)
+	}
+	return lastUsed, nil
+}
+
+// Marks the profile as used now
+func TouchLastUsed(profile string) error {
+	lastUsed, err := LastUsed()
+	if err != nil {
+		return err
+	}
+	lastUsed[profile] = time.Now()
+
+	data, err := json.MarshalIndent(lastUsed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode last used profiles: %w", err)
+	}
+	path, err := Path("last_used.json")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
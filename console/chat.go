@@ -0,0 +1,67 @@
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+type ChatKind int
+
+const (
+	// <alice> hello
+	Message ChatKind = iota
+	// [Server] hello, sent with say
+	Broadcast
+	// * alice waves, sent with /me
+	Action
+	Joined
+	Left
+)
+
+// A chat line of the console
+type Chat struct {
+	Kind   ChatKind
+	Time   string
+	Player string
+	Text   string
+}
+
+// Player names are up to 16 letters, digits and underscores
+// Accounts have at least 3, offline mode servers accept shorter ones
+// Bedrock players through Geyser may start with a dot or an asterisk
+const name = `[.*]?[A-Za-z0-9_]{1,16}`
+
+var (
+	// Signed chat in 1.19+ may be marked [Not Secure]
+	messageRegex   = regexp.MustCompile(`^(?:\[Not Secure\] )?<(` + name + `)> (.*)$`)
+	broadcastRegex = regexp.MustCompile(`^\[(Server|Rcon|` + name + `)\] (.*)$`)
+	actionRegex    = regexp.MustCompile(`^\* (` + name + `) (.*)$`)
+	joinRegex      = regexp.MustCompile(`^(` + name + `) (joined|left) the game$`)
+)
+
+// Returns the chat message in the line, if it's one
+// Only lines of the server and chat threads are chat
+func ParseChat(line Line) (Chat, bool) {
+	if line.Time == "" || (line.Thread != "" && line.Thread != "Server thread" && !strings.Contains(line.Thread, "Chat")) {
+		return Chat{}, false
+	}
+	msg := line.Message
+	if m := messageRegex.FindStringSubmatch(msg); m != nil {
+		return Chat{Kind: Message, Time: line.Time, Player: m[1], Text: m[2]}, true
+	}
+	if m := actionRegex.FindStringSubmatch(msg); m != nil {
+		return Chat{Kind: Action, Time: line.Time, Player: m[1], Text: m[2]}, true
+	}
+	if m := joinRegex.FindStringSubmatch(msg); m != nil {
+		kind := Joined
+		if m[2] == "left" {
+			kind = Left
+		}
+		return Chat{Kind: kind, Time: line.Time, Player: m[1]}, true
+	}
+	// Paper lines have no thread, and plugins log their name in brackets
+	if m := broadcastRegex.FindStringSubmatch(msg); m != nil && (line.Thread != "" || m[1] == "Server" || m[1] == "Rcon") {
+		return Chat{Kind: Broadcast, Time: line.Time, Player: m[1], Text: m[2]}, true
+	}
+	return Chat{}, false
+}
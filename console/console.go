@@ -0,0 +1,76 @@
+// Package console parses the lines of the server log
+//
+//	[12:34:56] [Server thread/INFO]: Done (3.2s)! For help, type "help"
+//	[12:34:56 WARN]: Can't keep up! Is the server overloaded?
+//
+// The first format is vanilla, the second one Spigot and Paper
+package console
+
+import (
+	"regexp"
+	"strings"
+)
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = map[string]Level{
+	"TRACE":   Debug,
+	"DEBUG":   Debug,
+	"INFO":    Info,
+	"WARN":    Warn,
+	"WARNING": Warn,
+	"ERROR":   Error,
+	"SEVERE":  Error,
+	"FATAL":   Error,
+}
+
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "DEBUG"
+	case Info:
+		return "INFO"
+	case Warn:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// A line of the console
+type Line struct {
+	Raw string
+	// Empty when the line has no header, e.g. a stack trace
+	Time    string
+	Thread  string
+	Level   Level
+	Message string
+}
+
+var (
+	vanillaRegex = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[([^\]]*)/([A-Z]+)\]:\s?(.*)$`)
+	paperRegex   = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}) ([A-Z]+)\]:\s?(.*)$`)
+)
+
+// Lines without a known header keep the level of the line before,
+// so they are filtered with the message they belong to
+func Parse(raw string, previous Level) Line {
+	if m := vanillaRegex.FindStringSubmatch(raw); m != nil {
+		if level, ok := levelNames[m[3]]; ok {
+			return Line{Raw: raw, Time: m[1], Thread: m[2], Level: level, Message: m[4]}
+		}
+	}
+	if m := paperRegex.FindStringSubmatch(raw); m != nil {
+		if level, ok := levelNames[m[2]]; ok {
+			return Line{Raw: raw, Time: m[1], Level: level, Message: m[3]}
+		}
+	}
+	return Line{Raw: raw, Level: previous, Message: strings.TrimRight(raw, " ")}
+}
@@ -0,0 +1,102 @@
+package console
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw      string
+		previous Level
+		line     Line
+	}{
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: <alice> hello",
+			line: Line{Time: "12:34:56", Thread: "Server thread", Level: Info, Message: "<alice> hello"},
+		},
+		{
+			raw:  "[12:34:56 WARN]: Can't keep up!",
+			line: Line{Time: "12:34:56", Level: Warn, Message: "Can't keep up!"},
+		},
+		{
+			raw:  "[01:02:03] [Worker-Main-1/ERROR]: Failed to load chunk",
+			line: Line{Time: "01:02:03", Thread: "Worker-Main-1", Level: Error, Message: "Failed to load chunk"},
+		},
+		{
+			raw:      "\tat net.minecraft.Main.main(Main.java:1)",
+			previous: Error,
+			line:     Line{Level: Error, Message: "\tat net.minecraft.Main.main(Main.java:1)"},
+		},
+		{
+			raw:      "[12:34:56] [Server thread/NOTICE]: unknown level",
+			previous: Info,
+			line:     Line{Level: Info, Message: "[12:34:56] [Server thread/NOTICE]: unknown level"},
+		},
+	}
+
+	for _, tc := range tests {
+		tc.line.Raw = tc.raw
+		if line := Parse(tc.raw, tc.previous); line != tc.line {
+			t.Errorf("%q: got %+v", tc.raw, line)
+		}
+	}
+}
+
+func TestParseChat(t *testing.T) {
+	tests := []struct {
+		raw  string
+		chat Chat
+		ok   bool
+	}{
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: <alice> hello there",
+			chat: Chat{Kind: Message, Time: "12:34:56", Player: "alice", Text: "hello there"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: [Not Secure] <bob_2> hi",
+			chat: Chat{Kind: Message, Time: "12:34:56", Player: "bob_2", Text: "hi"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56 INFO]: <.bedrock> hi",
+			chat: Chat{Kind: Message, Time: "12:34:56", Player: ".bedrock", Text: "hi"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Async Chat Thread - #0/INFO]: <alice> hello",
+			chat: Chat{Kind: Message, Time: "12:34:56", Player: "alice", Text: "hello"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: [Rcon] restarting soon",
+			chat: Chat{Kind: Broadcast, Time: "12:34:56", Player: "Rcon", Text: "restarting soon"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: [alice] from a command block",
+			chat: Chat{Kind: Broadcast, Time: "12:34:56", Player: "alice", Text: "from a command block"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: * alice waves",
+			chat: Chat{Kind: Action, Time: "12:34:56", Player: "alice", Text: "waves"},
+			ok:   true,
+		},
+		{
+			raw:  "[12:34:56] [Server thread/INFO]: bob left the game",
+			chat: Chat{Kind: Left, Time: "12:34:56", Player: "bob"},
+			ok:   true,
+		},
+		// Plugins log their name in brackets
+		{raw: "[12:34:56 INFO]: [Vault] Enabling Vault"},
+		{raw: "[12:34:56] [Worker-Main-1/INFO]: <alice> not chat"},
+		{raw: "[12:34:56] [Server thread/INFO]: Done (3.2s)!"},
+		{raw: "<alice> no header"},
+	}
+
+	for _, tc := range tests {
+		chat, ok := ParseChat(Parse(tc.raw, Info))
+		if chat != tc.chat || ok != tc.ok {
+			t.Errorf("%q: got %+v %v", tc.raw, chat, ok)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+// Package grammar describes the vanilla commands, so the prompt can show
+// hints and catch typos before they reach the server
+package grammar
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Commands of the latest Minecraft version we know
+// A file with the same format in the config dir replaces it
+//
+//go:embed commands.json
+var defaultData []byte
+
+type ArgType string
+
+const (
+	Literal ArgType = "literal"
+	Enum    ArgType = "enum"
+	// Any single word, e.g. item ids
+	Word ArgType = "word"
+	// Everything until the end, e.g. say <message>
+	Text   ArgType = "text"
+	Int    ArgType = "int"
+	Number ArgType = "number"
+	Bool   ArgType = "bool"
+	// Player name, uuid or selector
+	Target ArgType = "target"
+	// One coordinate, may be relative with ~ or ^
+	Coord ArgType = "coord"
+)
+
+var argTypes = []ArgType{Word, Text, Int, Number, Bool, Target, Coord}
+
+// Names that imply the type, e.g. [target]
+var typeNames = map[string]ArgType{
+	"target":  Target,
+	"targets": Target,
+	"player":  Target,
+	"message": Text,
+	"reason":  Text,
+}
+
+var (
+	selectorRegex      = regexp.MustCompile(`^@[aenprs](\[.*\])?$`)
+	playerRegex        = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+	uuidRegex          = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	coordRegex         = regexp.MustCompile(`^([~^](-?(\d+\.?\d*|\.\d+))?|-?(\d+\.?\d*|\.\d+))$`)
+	partialTargetRegex = regexp.MustCompile(`^(@[aenprs]?(\[.*)?|[A-Za-z0-9_-]*)$`)
+	partialCoordRegex  = regexp.MustCompile(`^[~^]?-?\d*\.?\d*$`)
+)
+
+type Arg struct {
+	Name     string
+	Type     ArgType
+	Values   []string
+	Optional bool
+}
+
+// The enums are cut, so the hint fits in a line
+func (a Arg) String() string {
+	name := a.Name
+	switch a.Type {
+	case Literal:
+		return a.Name
+	case Enum:
+		switch {
+		case len(a.Values) <= 3:
+			name = strings.Join(a.Values, "|")
+		case a.Name == "value":
+			name = strings.Join(a.Values[:2], "|") + "|..."
+		}
+	case Text:
+		name += "..."
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+// Checks a single word
+// A partial word is still being typed, so it only has to be a prefix
+func (a Arg) check(word string, partial bool) error {
+	switch a.Type {
+	case Literal:
+		if word == a.Name || (partial && strings.HasPrefix(a.Name, word)) {
+			return nil
+		}
+		return fmt.Errorf("expected %s, got %s", a.Name, word)
+	case Enum:
+		for _, value := range a.Values {
+			if word == value || (partial && strings.HasPrefix(value, word)) {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown %s %s, expected %s", a.Name, word, strings.Join(a.Values, ", "))
+	case Int:
+		if partial && (word == "" || word == "-") {
+			return nil
+		}
+		if _, err := strconv.Atoi(word); err != nil {
+			return fmt.Errorf("%s must be an integer, got %s", a.Name, word)
+		}
+	case Number:
+		if partial && (word == "" || word == "-") {
+			return nil
+		}
+		if _, err := strconv.ParseFloat(word, 64); err != nil {
+			return fmt.Errorf("%s must be a number, got %s", a.Name, word)
+		}
+	case Bool:
+		if word == "true" || word == "false" ||
+			(partial && (strings.HasPrefix("true", word) || strings.HasPrefix("false", word))) {
+			return nil
+		}
+		return fmt.Errorf("%s must be true or false, got %s", a.Name, word)
+	case Target:
+		if partial && partialTargetRegex.MatchString(word) {
+			return nil
+		}
+		if !selectorRegex.MatchString(word) && !playerRegex.MatchString(word) && !uuidRegex.MatchString(word) {
+			return fmt.Errorf("invalid %s %s", a.Name, word)
+		}
+	case Coord:
+		if partial && partialCoordRegex.MatchString(word) {
+			return nil
+		}
+		if !coordRegex.MatchString(word) {
+			return fmt.Errorf("invalid coordinate %s for %s", word, a.Name)
+		}
+	}
+	return nil
+}
+
+// One way to call a command, without the command name
+type Usage []Arg
+
+func (u Usage) String() string {
+	var parts []string
+	for _, arg := range u {
+		parts = append(parts, arg.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Returns how many args matched
+// complete requires all the args, partial allows the last word to be a prefix
+func (u Usage) match(args []string, complete, partial bool) (int, error) {
+	for i, arg := range u {
+		if i >= len(args) {
+			if !complete || arg.Optional {
+				return i, nil
+			}
+			return i, fmt.Errorf("missing %s", arg)
+		}
+		if arg.Type == Text {
+			return len(args), nil
+		}
+		if err := arg.check(args[i], partial && i == len(args)-1); err != nil {
+			return i, err
+		}
+	}
+	if len(args) > len(u) {
+		if len(u) == 0 {
+			return 0, fmt.Errorf("takes no arguments")
+		}
+		return len(u), fmt.Errorf("too many arguments, expected %s", u)
+	}
+	return len(args), nil
+}
+
+type Grammar struct {
+	// Minecraft version described by the file
+	Version  string
+	commands map[string][]Usage
+}
+
+type file struct {
+	Version string `json:"version"`
+	// Usages in the same format as the hints, e.g.
+	// "gamemode": ["<survival|creative|adventure|spectator> [target]"]
+	Commands map[string][]string `json:"commands"`
+}
+
+// Returns the grammar built into the executable
+func Default() *Grammar {
+	g, err := Parse(defaultData)
+	if err != nil {
+		panic(fmt.Sprintf("invalid builtin commands.json: %v", err))
+	}
+	return g
+}
+
+// Loads the grammar from path
+// Returns the builtin grammar if the file doesn't exist
+func Load(path string) (*Grammar, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read %s: %w", path, err)
+	}
+	g, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return g, nil
+}
+
+func Parse(data []byte) (*Grammar, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	g := &Grammar{Version: f.Version, commands: map[string][]Usage{}}
+	for name, usages := range f.Commands {
+		for _, s := range usages {
+			usage, err := parseUsage(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", name, s, err)
+			}
+			g.commands[name] = append(g.commands[name], usage)
+		}
+	}
+	return g, nil
+}
+
+// Tokens are literals, <required> or [optional] args
+// Args are a name, name:type, a|b|c or name:a|b|c
+func parseUsage(s string) (Usage, error) {
+	var usage Usage
+	for _, token := range strings.Fields(s) {
+		if len(usage) > 0 && usage[len(usage)-1].Type == Text {
+			return nil, fmt.Errorf("text must be the last argument")
+		}
+
+		var arg Arg
+		switch {
+		case strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">"):
+			token = token[1 : len(token)-1]
+		case strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]"):
+			token = token[1 : len(token)-1]
+			arg.Optional = true
+		default:
+			usage = append(usage, Arg{Name: token, Type: Literal})
+			continue
+		}
+		if !arg.Optional && len(usage) > 0 && usage[len(usage)-1].Optional {
+			return nil, fmt.Errorf("required %s after an optional argument", token)
+		}
+
+		name, kind, found := strings.Cut(token, ":")
+		if !found {
+			name, kind = token, ""
+			if strings.Contains(token, "|") {
+				name, kind = "value", token
+			}
+		}
+		arg.Name = name
+		switch {
+		case strings.Contains(kind, "|"):
+			arg.Type = Enum
+			arg.Values = strings.Split(kind, "|")
+		case kind != "":
+			arg.Type = ArgType(kind)
+			if !validType(arg.Type) {
+				return nil, fmt.Errorf("unknown type %s", kind)
+			}
+		case typeNames[name] != "":
+			arg.Type = typeNames[name]
+		default:
+			arg.Type = Word
+		}
+		usage = append(usage, arg)
+	}
+	return usage, nil
+}
+
+func validType(t ArgType) bool {
+	for _, known := range argTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the usages of a command, accepts /gamemode and minecraft:gamemode
+func (g *Grammar) usages(name string) ([]Usage, bool) {
+	name = strings.TrimPrefix(name, "/")
+	name = strings.TrimPrefix(name, "minecraft:")
+	usages, ok := g.commands[name]
+	return usages, ok
+}
+
+// Checks a command before sending it
+// Unknown commands are accepted, they may come from plugins
+func (g *Grammar) Check(input string) error {
+	args := Split(input)
+	if len(args) == 0 {
+		return nil
+	}
+	usages, ok := g.usages(args[0])
+	if !ok {
+		return nil
+	}
+	return matchAny(usages, args[1:], true, false)
+}
+
+// Returns the usages matching what was typed so far
+// The error tells what is already wrong
+func (g *Grammar) Hints(input string) ([]Usage, error) {
+	args := Split(input)
+	if len(args) == 0 {
+		return nil, nil
+	}
+	usages, ok := g.usages(args[0])
+	if !ok {
+		return nil, nil
+	}
+	// The last word is incomplete until a space is typed
+	typing := !strings.HasSuffix(input, " ")
+	if len(args) == 1 && typing {
+		return usages, nil
+	}
+
+	var matching []Usage
+	for _, usage := range usages {
+		if _, err := usage.match(args[1:], false, typing); err == nil {
+			matching = append(matching, usage)
+		}
+	}
+	if len(matching) == 0 {
+		return usages, matchAny(usages, args[1:], false, typing)
+	}
+	return matching, nil
+}
+
+// Returns the args that may follow args, the first being the command
+// ok is false for unknown commands
+func (g *Grammar) Next(args []string) ([]Arg, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	usages, ok := g.usages(args[0])
+	if !ok {
+		return nil, false
+	}
+	var next []Arg
+	for _, usage := range usages {
+		if n := len(args) - 1; n < len(usage) {
+			if _, err := usage.match(args[1:], false, false); err == nil {
+				next = append(next, usage[n])
+			}
+		}
+	}
+	return next, true
+}
+
+// Returns nil if any usage matches
+// Otherwise the error of the usage that went further
+func matchAny(usages []Usage, args []string, complete, partial bool) error {
+	var best error
+	bestMatched := -1
+	for _, usage := range usages {
+		matched, err := usage.match(args, complete, partial)
+		if err == nil {
+			return nil
+		}
+		if matched > bestMatched {
+			best, bestMatched = err, matched
+		}
+	}
+	return best
+}
+
+// Splits a command in words
+// Spaces inside brackets, braces or quotes don't split, e.g. @a[limit=1, sort=nearest]
+func Split(input string) []string {
+	var words []string
+	var word strings.Builder
+	depth := 0
+	var quote rune
+	for _, r := range input {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case (r == ']' || r == '}') && depth > 0:
+			depth--
+		case r == ' ' && depth == 0:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,91 @@
+package grammar
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	g := Default()
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"gamemode creative", true},
+		{"/gamemode creative @a[limit=1, sort=nearest]", true},
+		{"minecraft:gamemode spectator alice", true},
+		{"gamemode creatve", false},
+		{"gamemode", false},
+		{"gamemode creative alice bob", false},
+		{"say hello there", true},
+		{"give alice diamond 64", true},
+		{"give alice diamond lots", false},
+		{"tp 10 ~5 ^-2.5", true},
+		{"tp alice bob", true},
+		{"tp alice 10 x 20", false},
+		{"effect give @p speed infinite 2 true", true},
+		{"list", true},
+		{"list uuids", true},
+		{"time set noon", true},
+		{"time set 1000", true},
+		{"time query week", false},
+		{"someplugin anything goes", true},
+	}
+
+	for _, tc := range tests {
+		err := g.Check(tc.input)
+		if (err == nil) != tc.valid {
+			t.Errorf("%q: expected valid %v, got %v", tc.input, tc.valid, err)
+		}
+	}
+}
+
+func TestHints(t *testing.T) {
+	g := Default()
+	tests := []struct {
+		input string
+		hints []string
+		valid bool
+	}{
+		{"gamemode", []string{"<survival|creative|...> [target]"}, true},
+		{"gamemode cre", []string{"<survival|creative|...> [target]"}, true},
+		{"gamemode foo ", []string{"<survival|creative|...> [target]"}, false},
+		{"weather ", []string{"<clear|rain|thunder> [duration]"}, true},
+		{"time q", []string{"query <daytime|gametime|day>"}, true},
+		{"unknown ", nil, true},
+	}
+
+	for _, tc := range tests {
+		usages, err := g.Hints(tc.input)
+		var hints []string
+		for _, u := range usages {
+			hints = append(hints, u.String())
+		}
+		if !slices.Equal(hints, tc.hints) || (err == nil) != tc.valid {
+			t.Errorf("%q: got %q %v", tc.input, hints, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	g := Default()
+	next, ok := g.Next([]string{"time"})
+	if !ok || len(next) != 3 || next[0].Type != Literal || next[1].Type != Enum {
+		t.Errorf("Unexpected args %v", next)
+	}
+	next, _ = g.Next([]string{"gamemode", "creative"})
+	if len(next) != 1 || next[0].Type != Target {
+		t.Errorf("Unexpected args %v", next)
+	}
+	if _, ok := g.Next([]string{"unknown"}); ok {
+		t.Errorf("Unknown command should not be ok")
+	}
+}
+
+func TestParseUsage(t *testing.T) {
+	for _, s := range []string{"[a] <b>", "<msg:text> [b]", "<a:float>"} {
+		if _, err := parseUsage(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package history keeps the commands typed in the prompt between sessions
+package history
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"mctui/config"
+)
+
+// Older commands are dropped after this
+const MaxEntries = 1000
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// A file with one command per line, oldest first
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func Open(path string) *File {
+	return &File{path: path}
+}
+
+// Returns the history file of a server in the config directory
+// name is the profile, or host:port without one
+func Default(name string) (*File, error) {
+	dir, err := config.Path("history")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("can't create history directory: %w", err)
+	}
+	return Open(filepath.Join(dir, unsafeChars.ReplaceAllString(name, "_"))), nil
+}
+
+// Returns the commands, oldest first
+// A missing file is an empty history
+func (f *File) Load() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load()
+}
+
+func (f *File) load() ([]string, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read history: %w", err)
+	}
+	defer file.Close()
+
+	var commands []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			commands = append(commands, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read history: %w", err)
+	}
+	return commands, nil
+}
+
+// Adds a command to the file
+// Reads it again, so many instances don't lose each other commands
+func (f *File) Add(command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	commands, err := f.load()
+	if err != nil {
+		return err
+	}
+	commands = Append(commands, command)
+
+	data := strings.Join(commands, "\n") + "\n"
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0o600); err != nil {
+		return fmt.Errorf("can't save history: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("can't save history: %w", err)
+	}
+	return nil
+}
+
+// Adds command as the newest entry
+// An older copy is removed and the oldest entries are dropped past MaxEntries
+func Append(commands []string, command string) []string {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return commands
+	}
+	// Cloned, the screens below share the old array
+	commands = slices.DeleteFunc(slices.Clone(commands), func(c string) bool {
+		return c == command
+	})
+	commands = append(commands, command)
+	if len(commands) > MaxEntries {
+		commands = commands[len(commands)-MaxEntries:]
+	}
+	return commands
+}
@@ -0,0 +1,51 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFile(t *testing.T) {
+	f := Open(filepath.Join(t.TempDir(), "history"))
+
+	commands, err := f.Load()
+	if err != nil || len(commands) != 0 {
+		t.Fatalf("Expected empty history, got %v %v", commands, err)
+	}
+
+	for _, command := range []string{"list", "say hi", "list", " ", "time set day"} {
+		if err := f.Add(command); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commands, err = f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"say hi", "list", "time set day"}
+	if !slices.Equal(commands, expected) {
+		t.Errorf("Expected %v, got %v", expected, commands)
+	}
+}
+
+func TestAppendCap(t *testing.T) {
+	var commands []string
+	for i := 0; i < MaxEntries+10; i++ {
+		commands = Append(commands, fmt.Sprintf("say %d", i))
+	}
+	if len(commands) != MaxEntries || commands[0] != "say 10" {
+		t.Errorf("Unexpected history of %d entries starting with %s", len(commands), commands[0])
+	}
+}
+
+// The screens below keep a copy of the slice
+func TestAppendShared(t *testing.T) {
+	commands := []string{"list", "say hi", "time set day"}
+	copied := commands
+	Append(commands, "list")
+	if !slices.Equal(copied, []string{"list", "say hi", "time set day"}) {
+		t.Errorf("Append changed the copy: %v", copied)
+	}
+}
@@ -0,0 +1,133 @@
+// Package macro expands the aliases and macros from the config file
+//
+//	[aliases]
+//	gmc = "gamemode creative $1"
+//
+//	[macros]
+//	restart = ["say restarting in $1", "wait $1", "save-all", "!backup"]
+package macro
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"mctui/script"
+)
+
+// A command to send, or a pause when Wait is set
+type Step struct {
+	Command string
+	Wait    time.Duration
+}
+
+func (s Step) IsWait() bool {
+	return s.Command == ""
+}
+
+func (s Step) String() string {
+	if s.IsWait() {
+		return fmt.Sprintf("wait %s", s.Wait)
+	}
+	return s.Command
+}
+
+type Set struct {
+	// Replace a single command. Arguments without a $ go to the end
+	Aliases map[string]string
+	// Run many commands, one after the other
+	Macros map[string][]string
+}
+
+// Expands input if it starts with an alias or macro name
+// ok is false when it's a regular command
+func (s Set) Expand(input string) (steps []Step, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+	name, args := fields[0], fields[1:]
+
+	if template, found := s.Aliases[name]; found {
+		command, used, err := substitute(template, args)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		if !used && len(args) > 0 {
+			command += " " + strings.Join(args, " ")
+		}
+		if err := script.CheckCommand(command); err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		return []Step{{Command: command}}, true, nil
+	}
+
+	templates, found := s.Macros[name]
+	if !found {
+		return nil, false, nil
+	}
+	for _, template := range templates {
+		command, _, err := substitute(template, args)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		step, err := ParseStep(command)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %w", name, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, true, nil
+}
+
+// Turns "wait 5m" into a pause, anything else is a command
+// Waits without a unit are seconds
+func ParseStep(command string) (Step, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || fields[0] != "wait" {
+		if err := script.CheckCommand(command); err != nil {
+			return Step{}, err
+		}
+		return Step{Command: command}, nil
+	}
+	if len(fields) != 2 {
+		return Step{}, fmt.Errorf("wait needs a duration, e.g. wait 30s")
+	}
+	d, err := script.ParseDuration(fields[1])
+	if err != nil {
+		return Step{}, err
+	}
+	return Step{Wait: d}, nil
+}
+
+// Replaces $1 to $9 with the arguments, $@ with all of them and $$ with $
+// used tells if the template had any placeholder
+func substitute(template string, args []string) (result string, used bool, err error) {
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		if template[i] != '$' || i+1 >= len(template) {
+			b.WriteByte(template[i])
+			continue
+		}
+		next := template[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+		case next == '@':
+			b.WriteString(strings.Join(args, " "))
+			used = true
+		case next >= '1' && next <= '9':
+			n := int(next - '0')
+			if n > len(args) {
+				return "", false, fmt.Errorf("missing argument $%d", n)
+			}
+			b.WriteString(args[n-1])
+			used = true
+		default:
+			b.WriteByte('$')
+			continue
+		}
+		i++
+	}
+	return b.String(), used, nil
+}
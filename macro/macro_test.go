@@ -0,0 +1,62 @@
+package macro
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	s := Set{
+		Aliases: map[string]string{
+			"gmc":  "gamemode creative",
+			"heal": "effect give $1 instant_health 1 $2",
+			"cost": "say it costs $$5",
+			"dash": "!dashboard",
+		},
+		Macros: map[string][]string{
+			"restart": {"say restarting in $1", "wait $1", "save-all", "!backup"},
+			"shout":   {"say $@", "wait 10"},
+			"leave":   {"save-all", "!logout"},
+		},
+	}
+
+	tests := []struct {
+		input    string
+		steps    []Step
+		ok       bool
+		hasError bool
+	}{
+		{input: "list"},
+		{input: "gmc", steps: []Step{{Command: "gamemode creative"}}, ok: true},
+		{input: "gmc alice", steps: []Step{{Command: "gamemode creative alice"}}, ok: true},
+		{input: "heal alice 5", steps: []Step{{Command: "effect give alice instant_health 1 5"}}, ok: true},
+		{input: "heal", ok: true, hasError: true},
+		{input: "cost", steps: []Step{{Command: "say it costs $5"}}, ok: true},
+		{
+			input: "restart 5m",
+			steps: []Step{
+				{Command: "say restarting in 5m"},
+				{Wait: 5 * time.Minute},
+				{Command: "save-all"},
+				{Command: "!backup"},
+			},
+			ok: true,
+		},
+		{input: "restart soon", ok: true, hasError: true},
+		{input: "dash", ok: true, hasError: true},
+		{input: "leave", ok: true, hasError: true},
+		{
+			input: "shout hello there",
+			steps: []Step{{Command: "say hello there"}, {Wait: 10 * time.Second}},
+			ok:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		steps, ok, err := s.Expand(tc.input)
+		if !reflect.DeepEqual(steps, tc.steps) || ok != tc.ok || (err != nil) != tc.hasError {
+			t.Errorf("%q: got %v %v %v", tc.input, steps, ok, err)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"log"
 	"mctui/app"
 	"mctui/cli"
+	"mctui/config"
 	"os"
+	"strings"
 )
 
 func main() {
@@ -26,22 +28,75 @@ func main() {
 	}
 
 	// Parse CLI args
-	var err error
-	_ = kong.Parse(&cli.Args)
-	err = cli.Args.Validate()
+	ctx := kong.Parse(&cli.Args)
+	file, err := cli.Args.LoadProfile()
 	if err != nil {
-		panic(err.Error())
+		fatal(err)
+	}
+	app.SetMacros(file)
+	app.SetDashboard(file.Dashboard)
+
+	switch ctx.Command() {
+	case "exec <command>":
+		connectHeadless()
+		command := strings.Join(cli.Args.Exec.Command, " ")
+		err = app.Exec(os.Stdout, command, cli.Args.Exec.Password)
+	case "run <script>", "run <script> <args>":
+		connectHeadless()
+		r := cli.Args.Run
+		err = app.Run(os.Stdout, os.Stdin, r.Script, r.Args, r.Vars, r.Password, r.Yes)
+	case "backup create":
+		connectHeadless()
+		err = app.BackupCreate(os.Stdout, cli.Args.Backup.Password)
+	case "backup list":
+		connectHeadless()
+		err = app.BackupList(os.Stdout, cli.Args.Backup.Password, cli.Args.Backup.List.JSON)
+	case "backup restore <filename>":
+		connectHeadless()
+		err = app.BackupRestore(os.Stdout, cli.Args.Backup.Password, cli.Args.Backup.Restore.Filename)
+	default:
+		runTUI(file)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func runTUI(file *config.File) {
+	// Connect after the user picks a server
+	var model tea.Model
+	if cli.Args.PickServer {
+		model = app.InitialPickerModel(file)
+	} else {
+		if err := app.Connect(); err != nil {
+			fatal(err)
+		}
+		model = app.InitialModel()
 	}
 
 	// program := tea.NewProgram(app.InitialLoginModel())
 	program := tea.NewProgram(
-		app.InitialLoginModel(),
+		model,
 		tea.WithMouseCellMotion(),
 		tea.WithAltScreen(),
 	)
-	if err != nil {
+	if _, err := program.Run(); err != nil {
 		log.Printf("Error running program: %v", err)
+		fatal(err)
 	}
-	program.Run()
+}
+
+// Subcommands can't show the server picker
+func connectHeadless() {
+	if cli.Args.PickServer {
+		fatal(fmt.Errorf("many profiles configured, choose one with --profile"))
+	}
+	if err := app.Connect(); err != nil {
+		fatal(err)
+	}
+}
 
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "fatal:", err)
+	os.Exit(1)
 }
@@ -0,0 +1,332 @@
+// Package mcformat renders Minecraft formatted text in the terminal
+// Supports legacy § codes and JSON text components, like tellraw uses
+package mcformat
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Section sign used by the legacy codes, e.g. §a
+const sectionSign = '§'
+
+// Legacy color codes and the names used by JSON components
+var colorCodes = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+// Same values as the game
+var colorValues = map[string]string{
+	"black":        "#000000",
+	"dark_blue":    "#0000AA",
+	"dark_green":   "#00AA00",
+	"dark_aqua":    "#00AAAA",
+	"dark_red":     "#AA0000",
+	"dark_purple":  "#AA00AA",
+	"gold":         "#FFAA00",
+	"gray":         "#AAAAAA",
+	"dark_gray":    "#555555",
+	"blue":         "#5555FF",
+	"green":        "#55FF55",
+	"aqua":         "#55FFFF",
+	"red":          "#FF5555",
+	"light_purple": "#FF55FF",
+	"yellow":       "#FFFF55",
+	"white":        "#FFFFFF",
+}
+
+// Formatting of a piece of text
+// An empty color uses the base style
+type Style struct {
+	Color         string
+	Bold          bool
+	Italic        bool
+	Underlined    bool
+	Strikethrough bool
+	Obfuscated    bool
+}
+
+type Span struct {
+	Text  string
+	Style Style
+}
+
+// Splits text in spans with the same style
+// JSON components are parsed only if the whole text is one
+func Parse(text string) []Span {
+	if spans, ok := parseComponent(text); ok {
+		return spans
+	}
+	return parseLegacy(text, Style{})
+}
+
+// Returns the text without formatting
+func Strip(text string) string {
+	var b strings.Builder
+	for _, span := range Parse(text) {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
+
+// Renders the text with its colors on top of base
+func Render(text string, base lipgloss.Style) string {
+	var b strings.Builder
+	for _, span := range Parse(text) {
+		style := span.Style.apply(base)
+		// lipgloss pads multiline strings, so lines are rendered one by one
+		for i, line := range strings.Split(span.Text, "\n") {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			if line != "" {
+				b.WriteString(style.Render(line))
+			}
+		}
+	}
+	return b.String()
+}
+
+func (s Style) apply(base lipgloss.Style) lipgloss.Style {
+	style := base
+	if hex := colorValue(s.Color); hex != "" {
+		style = style.Foreground(lipgloss.Color(hex))
+	}
+	if s.Bold {
+		style = style.Bold(true)
+	}
+	if s.Italic {
+		style = style.Italic(true)
+	}
+	if s.Underlined {
+		style = style.Underline(true)
+	}
+	if s.Strikethrough {
+		style = style.Strikethrough(true)
+	}
+	// We can't scramble the text like the game does
+	if s.Obfuscated {
+		style = style.Faint(true)
+	}
+	return style
+}
+
+// Accepts color names and hex colors, e.g. #FF0000
+func colorValue(color string) string {
+	if hex, ok := colorValues[color]; ok {
+		return hex
+	}
+	if len(color) == 7 && color[0] == '#' {
+		return color
+	}
+	return ""
+}
+
+// Parses § codes, starting with style
+func parseLegacy(text string, style Style) []Span {
+	var spans []Span
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			spans = append(spans, Span{Text: current.String(), Style: style})
+			current.Reset()
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != sectionSign || i+1 >= len(runes) {
+			current.WriteRune(runes[i])
+			continue
+		}
+		code := toLower(runes[i+1])
+		i++
+		flush()
+
+		switch {
+		case colorCodes[code] != "":
+			// Colors reset the formatting, like in the game
+			style = Style{Color: colorCodes[code]}
+		case code == 'x' && i+12 < len(runes):
+			// Hex color used by Spigot, e.g. §x§f§f§0§0§0§0
+			if hex, ok := parseHexCode(runes[i+1 : i+13]); ok {
+				style = Style{Color: hex}
+				i += 12
+			}
+		case code == 'k':
+			style.Obfuscated = true
+		case code == 'l':
+			style.Bold = true
+		case code == 'm':
+			style.Strikethrough = true
+		case code == 'n':
+			style.Underlined = true
+		case code == 'o':
+			style.Italic = true
+		case code == 'r':
+			style = Style{}
+		}
+		// Unknown codes are dropped, the game does the same
+	}
+	flush()
+	return spans
+}
+
+func parseHexCode(runes []rune) (string, bool) {
+	hex := []rune{'#'}
+	for i := 0; i < len(runes); i += 2 {
+		if runes[i] != sectionSign || !strings.ContainsRune("0123456789abcdef", toLower(runes[i+1])) {
+			return "", false
+		}
+		hex = append(hex, toLower(runes[i+1]))
+	}
+	return string(hex), true
+}
+
+func toLower(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
+// A JSON text component
+// Booleans are pointers, so children can tell unset from false
+type component struct {
+	Text          string            `json:"text"`
+	Translate     string            `json:"translate"`
+	With          []json.RawMessage `json:"with"`
+	Color         string            `json:"color"`
+	Bold          *bool             `json:"bold"`
+	Italic        *bool             `json:"italic"`
+	Underlined    *bool             `json:"underlined"`
+	Strikethrough *bool             `json:"strikethrough"`
+	Obfuscated    *bool             `json:"obfuscated"`
+	Extra         []json.RawMessage `json:"extra"`
+}
+
+func parseComponent(text string) ([]Span, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	var spans []Span
+	if !walkComponent(json.RawMessage(trimmed), Style{}, &spans, true) {
+		return nil, false
+	}
+	return spans, true
+}
+
+// Appends the spans of a component and its children, which inherit its style
+// root is strict, so JSON that isn't a component is shown as it is
+func walkComponent(raw json.RawMessage, parent Style, spans *[]Span, root bool) bool {
+	// Strings, numbers and booleans are plain text
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return false
+	}
+	switch value := value.(type) {
+	case string:
+		if root {
+			return false
+		}
+		*spans = append(*spans, parseLegacy(value, parent)...)
+		return true
+	case float64, bool:
+		if root {
+			return false
+		}
+		*spans = append(*spans, Span{Text: fmt.Sprint(value), Style: parent})
+		return true
+	}
+
+	// Arrays are a component with the others as extra
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		if len(list) == 0 {
+			return !root
+		}
+		first := len(*spans)
+		if !walkComponent(list[0], parent, spans, false) {
+			return false
+		}
+		style := parent
+		if len(*spans) > first {
+			style = (*spans)[first].Style
+		}
+		for _, child := range list[1:] {
+			if !walkComponent(child, style, spans, false) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var c component
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return false
+	}
+	if root && c.Text == "" && c.Translate == "" && len(c.Extra) == 0 {
+		return false
+	}
+
+	style := parent
+	if c.Color != "" {
+		style.Color = c.Color
+	}
+	setFlag(&style.Bold, c.Bold)
+	setFlag(&style.Italic, c.Italic)
+	setFlag(&style.Underlined, c.Underlined)
+	setFlag(&style.Strikethrough, c.Strikethrough)
+	setFlag(&style.Obfuscated, c.Obfuscated)
+
+	if c.Translate != "" {
+		// We don't have the translations, show the key and its arguments
+		var args []string
+		for _, arg := range c.With {
+			var argSpans []Span
+			walkComponent(arg, style, &argSpans, false)
+			var b strings.Builder
+			for _, span := range argSpans {
+				b.WriteString(span.Text)
+			}
+			args = append(args, b.String())
+		}
+		text := c.Translate
+		if len(args) > 0 {
+			text += " " + strings.Join(args, " ")
+		}
+		*spans = append(*spans, Span{Text: text, Style: style})
+	}
+	*spans = append(*spans, parseLegacy(c.Text, style)...)
+	for _, child := range c.Extra {
+		if !walkComponent(child, style, spans, false) {
+			return false
+		}
+	}
+	return true
+}
+
+func setFlag(flag *bool, value *bool) {
+	if value != nil {
+		*flag = *value
+	}
+}
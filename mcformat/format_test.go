@@ -0,0 +1,73 @@
+package mcformat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Span
+	}{
+		{
+			input:    "plain text",
+			expected: []Span{{Text: "plain text"}},
+		},
+		{
+			input: "§aGreen §lbold§r reset",
+			expected: []Span{
+				{Text: "Green ", Style: Style{Color: "green"}},
+				{Text: "bold", Style: Style{Color: "green", Bold: true}},
+				{Text: " reset"},
+			},
+		},
+		{
+			// Colors reset the formatting
+			input: "§l§6Gold",
+			expected: []Span{
+				{Text: "Gold", Style: Style{Color: "gold"}},
+			},
+		},
+		{
+			input: "§x§F§F§0§0§8§8hex",
+			expected: []Span{
+				{Text: "hex", Style: Style{Color: "#ff0088"}},
+			},
+		},
+		{
+			input: `{"text":"Hi ","color":"red","bold":true,"extra":[{"text":"there","bold":false},"!"]}`,
+			expected: []Span{
+				{Text: "Hi ", Style: Style{Color: "red", Bold: true}},
+				{Text: "there", Style: Style{Color: "red"}},
+				{Text: "!", Style: Style{Color: "red", Bold: true}},
+			},
+		},
+		{
+			input: `[{"text":"a","italic":true},"b",3]`,
+			expected: []Span{
+				{Text: "a", Style: Style{Italic: true}},
+				{Text: "b", Style: Style{Italic: true}},
+				{Text: "3", Style: Style{Italic: true}},
+			},
+		},
+		{
+			// Not a component
+			input:    `{"health": 20}`,
+			expected: []Span{{Text: `{"health": 20}`}},
+		},
+	}
+
+	for _, tc := range tests {
+		result := Parse(tc.input)
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%q:\nExpected: %+v\nGot: %+v", tc.input, tc.expected, result)
+		}
+	}
+}
+
+func TestStrip(t *testing.T) {
+	if result := Strip("§6[§eEssentials§6]§r Done"); result != "[Essentials] Done" {
+		t.Errorf("Unexpected %q", result)
+	}
+}
@@ -0,0 +1,141 @@
+// Package perf reads performance numbers from the server answers
+//
+// Paper and Spigot have tps (and Paper mspt), Forge has forge tps, and
+// vanilla only has debug start/stop. mctui-server may also have /metrics
+package perf
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Numbers at a point in time, NaN when the source doesn't have them
+type Sample struct {
+	Time time.Time
+	TPS  float64
+	// Milliseconds per tick
+	MSPT float64
+	// Megabytes
+	HeapUsed float64
+	HeapMax  float64
+	Chunks   float64
+}
+
+func NewSample(t time.Time) Sample {
+	nan := math.NaN()
+	return Sample{Time: t, TPS: nan, MSPT: nan, HeapUsed: nan, HeapMax: nan, Chunks: nan}
+}
+
+// True when the source gave no numbers
+func (s Sample) Empty() bool {
+	for _, v := range []float64{s.TPS, s.MSPT, s.HeapUsed, s.HeapMax, s.Chunks} {
+		if !math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// Used heap in percent, NaN if unknown
+func (s Sample) HeapPercent() float64 {
+	if math.IsNaN(s.HeapUsed) || math.IsNaN(s.HeapMax) || s.HeapMax <= 0 {
+		return math.NaN()
+	}
+	return s.HeapUsed / s.HeapMax * 100
+}
+
+var (
+	numberRegex = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	// Overall: Mean tick time: 1.234 ms. Mean TPS: 20.000
+	forgeOldRegex = regexp.MustCompile(`Overall\s*:\s*Mean tick time: ([\d.]+) ms\. Mean TPS: ([\d.]+)`)
+	// Overall: 20.000 TPS (1.234 ms/tick)
+	forgeRegex = regexp.MustCompile(`Overall\s*:\s*([\d.]+) TPS \(([\d.]+) ms/tick\)`)
+	// Stopped tick profiling after 10.00 seconds and 200 ticks (20.00 ticks per second)
+	debugRegex = regexp.MustCompile(`\(([\d.]+) ticks per second\)`)
+)
+
+// Returns the first number after the first colon
+func firstNumberAfterColon(output string) (float64, bool) {
+	_, after, found := strings.Cut(output, ":")
+	if !found {
+		return 0, false
+	}
+	match := numberRegex.FindString(after)
+	if match == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(match, 64)
+	return v, err == nil
+}
+
+// TPS from last 1m, 5m, 15m: 20.0, 20.0, 20.0
+// Paper shows *20.0 when the server catches up
+func ParseTPS(output string, s *Sample) bool {
+	if !strings.Contains(output, "TPS from last") {
+		return false
+	}
+	v, ok := firstNumberAfterColon(output)
+	if ok {
+		s.TPS = math.Min(v, 20)
+	}
+	return ok
+}
+
+// Server tick times (avg/min/max) from last 5s, 10s, 1m:
+// ◴ 1.2/0.8/3.4, 1.1/0.7/3.4, 1.0/0.5/5.6
+func ParseMSPT(output string, s *Sample) bool {
+	if !strings.Contains(output, "tick times") {
+		return false
+	}
+	v, ok := firstNumberAfterColon(output)
+	if ok {
+		s.MSPT = v
+	}
+	return ok
+}
+
+func ParseForgeTPS(output string, s *Sample) bool {
+	if m := forgeOldRegex.FindStringSubmatch(output); m != nil {
+		s.MSPT, _ = strconv.ParseFloat(m[1], 64)
+		s.TPS, _ = strconv.ParseFloat(m[2], 64)
+		return true
+	}
+	if m := forgeRegex.FindStringSubmatch(output); m != nil {
+		s.TPS, _ = strconv.ParseFloat(m[1], 64)
+		s.MSPT, _ = strconv.ParseFloat(m[2], 64)
+		return true
+	}
+	return false
+}
+
+// Output of debug stop
+func ParseDebug(output string, s *Sample) bool {
+	m := debugRegex.FindStringSubmatch(output)
+	if m == nil {
+		return false
+	}
+	s.TPS, _ = strconv.ParseFloat(m[1], 64)
+	return true
+}
+
+var bars = []rune("▁▂▃▄▅▆▇█")
+
+// One bar per value between low and high, a space for NaN
+func Sparkline(values []float64, low, high float64) []rune {
+	line := make([]rune, len(values))
+	for i, v := range values {
+		if math.IsNaN(v) {
+			line[i] = ' '
+			continue
+		}
+		level := 0
+		if high > low {
+			level = int((v - low) / (high - low) * float64(len(bars)-1))
+		}
+		line[i] = bars[max(0, min(level, len(bars)-1))]
+	}
+	return line
+}
@@ -0,0 +1,65 @@
+package perf
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		output string
+		parse  func(string, *Sample) bool
+		tps    float64
+		mspt   float64
+		ok     bool
+	}{
+		{output: "TPS from last 1m, 5m, 15m: 19.5, 20.0, 20.0", parse: ParseTPS, tps: 19.5, mspt: math.NaN(), ok: true},
+		{output: "TPS from last 1m, 5m, 15m: *20.5, *20.0, 20.0", parse: ParseTPS, tps: 20, mspt: math.NaN(), ok: true},
+		{output: "Unknown command. Type \"/help\" for help.", parse: ParseTPS, tps: math.NaN(), mspt: math.NaN()},
+		{
+			output: "Server tick times (avg/min/max) from last 5s, 10s, 1m:\n◴ 12.3/0.8/3.4, 1.1/0.7/3.4, 1.0/0.5/5.6",
+			parse:  ParseMSPT, tps: math.NaN(), mspt: 12.3, ok: true,
+		},
+		{
+			output: "Dim  0 (overworld): Mean tick time: 1.000 ms. Mean TPS: 20.000\nOverall: Mean tick time: 61.234 ms. Mean TPS: 16.330",
+			parse:  ParseForgeTPS, tps: 16.33, mspt: 61.234, ok: true,
+		},
+		{output: "Overall: 19.500 TPS (51.282 ms/tick)", parse: ParseForgeTPS, tps: 19.5, mspt: 51.282, ok: true},
+		{
+			output: "Stopped tick profiling after 10.05 seconds and 201 ticks (20.00 ticks per second)",
+			parse:  ParseDebug, tps: 20, mspt: math.NaN(), ok: true,
+		},
+		{output: "Started tick profiling", parse: ParseDebug, tps: math.NaN(), mspt: math.NaN()},
+	}
+
+	for _, tc := range tests {
+		s := NewSample(time.Now())
+		ok := tc.parse(tc.output, &s)
+		if ok != tc.ok || !same(s.TPS, tc.tps) || !same(s.MSPT, tc.mspt) {
+			t.Errorf("%q: got %v %v %v", tc.output, s.TPS, s.MSPT, ok)
+		}
+	}
+}
+
+func same(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+func TestHeapPercent(t *testing.T) {
+	s := NewSample(time.Now())
+	if !math.IsNaN(s.HeapPercent()) {
+		t.Errorf("Expected NaN without heap")
+	}
+	s.HeapUsed, s.HeapMax = 1024, 4096
+	if s.HeapPercent() != 25 {
+		t.Errorf("Expected 25%%, got %v", s.HeapPercent())
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	line := Sparkline([]float64{0, 10, 20, math.NaN(), 30, -5}, 0, 20)
+	if string(line) != "▁▄█ █▁" {
+		t.Errorf("Unexpected sparkline %q", string(line))
+	}
+}
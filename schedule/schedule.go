@@ -0,0 +1,219 @@
+// Package schedule runs commands later or repeatedly, used by !schedule
+//
+//	in 10m say the event starts now
+//	every 30m save-all
+//	cron 0 4 * * mon !backup
+//
+// Durations without a unit are seconds, see package script
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"mctui/script"
+)
+
+// When a job runs
+type When interface {
+	// Returns the first run after t, zero when there are no more
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// Runs once at a given time
+type once struct {
+	at time.Time
+	// As typed, e.g. 10m
+	delay time.Duration
+}
+
+func (o once) Next(t time.Time) time.Time {
+	if t.Before(o.at) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+func (o once) String() string {
+	return fmt.Sprintf("in %s", o.delay)
+}
+
+// Runs every d, counting from the last run
+type interval time.Duration
+
+func (d interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
+
+func (d interval) String() string {
+	return fmt.Sprintf("every %s", time.Duration(d))
+}
+
+// Shortest interval, so a typo doesn't flood the server
+const MinInterval = time.Second
+
+// Splits the text after !schedule in when and command
+func Parse(text string, now time.Time) (When, string, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, "", fmt.Errorf("missing schedule, e.g. every 30m save-all")
+	}
+
+	var when When
+	var rest []string
+	switch kind := strings.ToLower(fields[0]); kind {
+	case "in", "every":
+		if len(fields) < 2 {
+			return nil, "", fmt.Errorf("missing duration after %s", kind)
+		}
+		d, err := script.ParseDuration(fields[1])
+		if err != nil {
+			return nil, "", err
+		}
+		if kind == "in" {
+			when = once{at: now.Add(d), delay: d}
+		} else {
+			if d < MinInterval {
+				return nil, "", fmt.Errorf("interval must be at least %s", MinInterval)
+			}
+			when = interval(d)
+		}
+		rest = fields[2:]
+	case "cron":
+		if len(fields) < 6 {
+			return nil, "", fmt.Errorf("cron needs 5 fields, e.g. cron */15 * * * * save-all")
+		}
+		c, err := ParseCron(strings.Join(fields[1:6], " "))
+		if err != nil {
+			return nil, "", err
+		}
+		when = c
+		rest = fields[6:]
+	default:
+		return nil, "", fmt.Errorf("unknown schedule %s, use in, every or cron", fields[0])
+	}
+
+	if len(rest) == 0 {
+		return nil, "", fmt.Errorf("missing command to run %s", when)
+	}
+	command := strings.Join(rest, " ")
+	if err := script.CheckCommand(command); err != nil {
+		return nil, "", err
+	}
+	return when, command, nil
+}
+
+// A scheduled command
+type Job struct {
+	ID      int
+	When    When
+	Command string
+	// Zero once the job is done
+	Next   time.Time
+	Paused bool
+	Runs   int
+	// Output of the last run
+	Last   string
+	Failed bool
+}
+
+// Jobs of a session, kept in memory
+type List struct {
+	jobs   []*Job
+	lastID int
+}
+
+func (l *List) Add(when When, command string, now time.Time) *Job {
+	l.lastID++
+	j := &Job{ID: l.lastID, When: when, Command: command, Next: when.Next(now)}
+	l.jobs = append(l.jobs, j)
+	return j
+}
+
+// Returns the jobs in the order they were added
+func (l *List) Jobs() []*Job {
+	return slices.Clone(l.jobs)
+}
+
+func (l *List) Len() int {
+	return len(l.jobs)
+}
+
+func (l *List) Get(id int) *Job {
+	for _, j := range l.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+func (l *List) Remove(id int) bool {
+	before := len(l.jobs)
+	l.jobs = slices.DeleteFunc(l.jobs, func(j *Job) bool { return j.ID == id })
+	return len(l.jobs) != before
+}
+
+// A resumed job skips the runs it missed, except for one-off jobs
+func (l *List) SetPaused(id int, paused bool, now time.Time) {
+	j := l.Get(id)
+	if j == nil {
+		return
+	}
+	j.Paused = paused
+	if !paused {
+		j.skipMissed(now)
+	}
+}
+
+// Returns the jobs to run now and moves them to their next run
+// Finished one-off jobs are removed
+func (l *List) Due(now time.Time) []Job {
+	var due []Job
+	for _, j := range l.jobs {
+		if j.Paused || j.Next.IsZero() || j.Next.After(now) {
+			continue
+		}
+		j.Runs++
+		due = append(due, *j)
+		j.Next = j.When.Next(j.Next)
+		j.skipMissed(now)
+	}
+	l.jobs = slices.DeleteFunc(l.jobs, func(j *Job) bool { return j.Next.IsZero() })
+	return due
+}
+
+// Records the result of a run, if the job is still there
+func (l *List) Finished(id int, output string, failed bool) {
+	if j := l.Get(id); j != nil {
+		j.Last = output
+		j.Failed = failed
+	}
+}
+
+// Returns the closest run of the active jobs, zero if none
+func (l *List) NextRun() time.Time {
+	var next time.Time
+	for _, j := range l.jobs {
+		if j.Paused || j.Next.IsZero() {
+			continue
+		}
+		if next.IsZero() || j.Next.Before(next) {
+			next = j.Next
+		}
+	}
+	return next
+}
+
+// e.g. after the laptop slept, run once and not for every missed interval
+func (j *Job) skipMissed(now time.Time) {
+	if _, ok := j.When.(once); ok {
+		return
+	}
+	for !j.Next.IsZero() && !j.Next.After(now) {
+		j.Next = j.When.Next(j.Next)
+	}
+}
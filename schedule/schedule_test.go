@@ -0,0 +1,135 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// A saturday
+var now = time.Date(2026, 10, 17, 13, 20, 30, 0, time.UTC)
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2026, 10, 17, 13, 21, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 10, 17, 13, 30, 0, 0, time.UTC)},
+		{"0 4 * * *", time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)},
+		{"0 4 * * mon", time.Date(2026, 10, 19, 4, 0, 0, 0, time.UTC)},
+		{"30 9 1 jan *", time.Date(2027, 1, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		// Both days restricted, either one matches
+		{"0 12 1 * fri", time.Date(2026, 10, 23, 12, 0, 0, 0, time.UTC)},
+		// A day starting with * is not restricted, both must match
+		{"0 0 */2 * tue", time.Date(2026, 10, 27, 0, 0, 0, 0, time.UTC)},
+		{"0 8-18/5 * * *", time.Date(2026, 10, 17, 18, 0, 0, 0, time.UTC)},
+		{"5,50 13 * * *", time.Date(2026, 10, 17, 13, 50, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tc := range tests {
+		c, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tc.expr, err)
+			continue
+		}
+		if next := c.Next(now); !next.Equal(tc.next) {
+			t.Errorf("%q: expected %v, got %v", tc.expr, tc.next, next)
+		}
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text     string
+		when     string
+		command  string
+		hasError bool
+	}{
+		{text: "in 10m say hello world", when: "in 10m0s", command: "say hello world"},
+		{text: "every 30m save-all", when: "every 30m0s", command: "save-all"},
+		{text: "every 90 !backup", when: "every 1m30s", command: "!backup"},
+		{text: "cron */5 * * * * save-all", when: "cron */5 * * * *", command: "save-all"},
+		{text: "", hasError: true},
+		{text: "every 30m", hasError: true},
+		{text: "every 0 list", hasError: true},
+		{text: "in soon say hi", hasError: true},
+		{text: "cron * * * save-all", hasError: true},
+		{text: "tomorrow save-all", hasError: true},
+		{text: "every 1h !restore latest", when: "every 1h0m0s", command: "!restore latest"},
+		{text: "every 1h !export", hasError: true},
+		{text: "in 5m !restore", hasError: true},
+	}
+
+	for _, tc := range tests {
+		when, command, err := Parse(tc.text, now)
+		if (err != nil) != tc.hasError {
+			t.Errorf("%q: unexpected error %v", tc.text, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if when.String() != tc.when || command != tc.command {
+			t.Errorf("%q: got %q %q", tc.text, when, command)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	var l List
+	in, _, _ := Parse("in 1m say hi", now)
+	every, _, _ := Parse("every 10m save-all", now)
+	l.Add(in, "say hi", now)
+	saves := l.Add(every, "save-all", now)
+
+	if due := l.Due(now); len(due) != 0 {
+		t.Fatalf("Expected nothing to run, got %v", due)
+	}
+	if next := l.NextRun(); !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("Unexpected next run %v", next)
+	}
+
+	due := l.Due(now.Add(time.Minute))
+	if len(due) != 1 || due[0].Command != "say hi" {
+		t.Fatalf("Expected say hi, got %v", due)
+	}
+	if l.Len() != 1 {
+		t.Errorf("One-off job should be removed, got %d jobs", l.Len())
+	}
+
+	// Missed runs are skipped
+	due = l.Due(now.Add(35 * time.Minute))
+	if len(due) != 1 || due[0].Runs != 1 {
+		t.Fatalf("Expected one run of save-all, got %v", due)
+	}
+	if !saves.Next.Equal(now.Add(40 * time.Minute)) {
+		t.Errorf("Unexpected next run %v", saves.Next)
+	}
+
+	l.SetPaused(saves.ID, true, now)
+	if due := l.Due(now.Add(time.Hour)); len(due) != 0 {
+		t.Errorf("Paused job should not run")
+	}
+	l.SetPaused(saves.ID, false, now.Add(time.Hour))
+	if !saves.Next.Equal(now.Add(70 * time.Minute)) {
+		t.Errorf("Unexpected next run after resume %v", saves.Next)
+	}
+
+	l.Finished(saves.ID, "Saved the game", false)
+	if saves.Last != "Saved the game" {
+		t.Errorf("Result not recorded")
+	}
+	if !l.Remove(saves.ID) || l.Len() != 0 || l.Remove(saves.ID) {
+		t.Errorf("Remove failed")
+	}
+}
@@ -0,0 +1,304 @@
+// Package script parses files of commands and tasks, run by mctui run and !run
+//
+//	# Reset the event world
+//	set world event
+//	on-error continue
+//	say Resetting $world in 10 seconds
+//	sleep 10s
+//	confirm "Restore the latest backup?"
+//	!restore latest
+//
+// Lines are commands or tasks, sent like when typed in the prompt,
+// except for these directives:
+//
+//   - sleep <duration> pauses, numbers without a unit are seconds. wait is the same
+//   - confirm "<question>" asks before going on
+//   - set <name> <value> sets a variable, used as $name or ${name}
+//   - on-error stop|continue tells what to do when a command fails. Stops by default
+//
+// $1 to $9 are the script arguments and $$ is a literal $
+// Commands of the TUI itself, e.g. !export, are rejected
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Kind int
+
+const (
+	Command Kind = iota
+	Sleep
+	Confirm
+	// Handled by the runner, never returned by Next
+	set
+	onError
+)
+
+// A line of the script
+type Step struct {
+	Kind Kind
+	// Line number, starting at 1
+	Line    int
+	Command string
+	Sleep   time.Duration
+	// Question of a confirm
+	Prompt string
+	// Raw text, variables are expanded by the runner
+	text string
+}
+
+func (s Step) String() string {
+	switch s.Kind {
+	case Sleep:
+		return fmt.Sprintf("sleep %s", s.Sleep)
+	case Confirm:
+		return fmt.Sprintf("confirm %q", s.Prompt)
+	default:
+		return s.Command
+	}
+}
+
+type Script struct {
+	Name  string
+	steps []Step
+}
+
+var nameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Parses the script, so syntax errors show up before anything runs
+func Parse(name string, r io.Reader) (*Script, error) {
+	s := &Script{Name: name}
+	scanner := bufio.NewScanner(r)
+	num := 0
+	for scanner.Scan() {
+		num++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		step, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, num, err)
+		}
+		step.Line = num
+		s.steps = append(s.steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("can't read %s: %w", name, err)
+	}
+	return s, nil
+}
+
+func parseLine(text string) (Step, error) {
+	keyword, rest, _ := strings.Cut(text, " ")
+	rest = strings.TrimSpace(rest)
+	switch keyword {
+	case "sleep", "wait":
+		if rest == "" || strings.Contains(rest, " ") {
+			return Step{}, fmt.Errorf("%s needs a duration, e.g. %s 30s", keyword, keyword)
+		}
+		// Checked now unless it comes from a variable
+		if !strings.Contains(rest, "$") {
+			if _, err := ParseDuration(rest); err != nil {
+				return Step{}, err
+			}
+		}
+		return Step{Kind: Sleep, text: rest}, nil
+	case "confirm":
+		prompt, err := strconv.Unquote(rest)
+		if err != nil {
+			return Step{}, fmt.Errorf(`confirm needs a quoted question, e.g. confirm "Go on?"`)
+		}
+		return Step{Kind: Confirm, text: prompt}, nil
+	case "set":
+		name, _, _ := strings.Cut(rest, " ")
+		if !nameRegex.MatchString(name) {
+			return Step{}, fmt.Errorf("invalid variable name %q", name)
+		}
+		return Step{Kind: set, text: rest}, nil
+	case "on-error":
+		if rest != "stop" && rest != "continue" {
+			return Step{}, fmt.Errorf("on-error must be stop or continue")
+		}
+		return Step{Kind: onError, text: rest}, nil
+	default:
+		if err := CheckCommand(text); err != nil {
+			return Step{}, err
+		}
+		return Step{Kind: Command, text: text}, nil
+	}
+}
+
+// Commands of the TUI itself, e.g. screens. The server doesn't know them
+// !restore <name> is fine, only the bare one opens the backup screen
+var clientCommands = []string{"!restore", "!logout", "!export", "!run", "!schedule", "!players", "!dashboard"}
+
+// Rejects the client commands, scripts and macros only send to the server
+func CheckCommand(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || (fields[0] == "!restore" && len(fields) > 1) {
+		return nil
+	}
+	if slices.Contains(clientCommands, fields[0]) {
+		return fmt.Errorf("%s only works when typed in the prompt", fields[0])
+	}
+	return nil
+}
+
+// Numbers without a unit are seconds, otherwise like time.ParseDuration
+func ParseDuration(s string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(s); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid duration %s", s)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %s", s)
+	}
+	return d, nil
+}
+
+// Returns the steps of a run, one at a time
+type Runner struct {
+	script *Script
+	next   int
+	args   []string
+	vars   map[string]string
+	// Set by on-error continue
+	continueOnError bool
+}
+
+// args are $1 to $9, vars are the initial variables
+func (s *Script) Runner(args []string, vars map[string]string) *Runner {
+	r := &Runner{script: s, args: args, vars: map[string]string{}}
+	for k, v := range vars {
+		r.vars[k] = v
+	}
+	return r
+}
+
+func (r *Runner) Name() string {
+	return r.script.Name
+}
+
+// Tells if the run goes on after a failed command
+func (r *Runner) ContinueOnError() bool {
+	return r.continueOnError
+}
+
+// Returns the next command, sleep or confirm, with the variables expanded
+// ok is false at the end of the script
+func (r *Runner) Next() (step Step, ok bool, err error) {
+	for r.next < len(r.script.steps) {
+		step = r.script.steps[r.next]
+		r.next++
+
+		text, err := r.expand(step.text)
+		if err != nil {
+			return Step{}, false, fmt.Errorf("%s:%d: %w", r.script.Name, step.Line, err)
+		}
+		switch step.Kind {
+		case set:
+			name, value, _ := strings.Cut(text, " ")
+			r.vars[name] = strings.TrimSpace(value)
+		case onError:
+			r.continueOnError = text == "continue"
+		case Sleep:
+			if step.Sleep, err = ParseDuration(text); err != nil {
+				return Step{}, false, fmt.Errorf("%s:%d: %w", r.script.Name, step.Line, err)
+			}
+			return step, true, nil
+		case Confirm:
+			step.Prompt = text
+			return step, true, nil
+		default:
+			// Variables may hide a client command
+			if err := CheckCommand(text); err != nil {
+				return Step{}, false, fmt.Errorf("%s:%d: %w", r.script.Name, step.Line, err)
+			}
+			step.Command = text
+			return step, true, nil
+		}
+	}
+	return Step{}, false, nil
+}
+
+// Replaces $name, ${name}, $1 to $9 and $$
+func (r *Runner) expand(text string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '$' || i+1 >= len(text) {
+			b.WriteByte(text[i])
+			continue
+		}
+		next := text[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next >= '1' && next <= '9':
+			n := int(next - '0')
+			if n > len(r.args) {
+				return "", fmt.Errorf("missing argument $%d", n)
+			}
+			b.WriteString(r.args[n-1])
+			i++
+		case next == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed ${")
+			}
+			value, err := r.variable(text[i+2 : i+end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i += end
+		default:
+			end := i + 1
+			for end < len(text) && isNameChar(text[end], end == i+1) {
+				end++
+			}
+			if end == i+1 {
+				// A lone $, e.g. in a price
+				b.WriteByte('$')
+				continue
+			}
+			value, err := r.variable(text[i+1 : end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end - 1
+		}
+	}
+	return b.String(), nil
+}
+
+func (r *Runner) variable(name string) (string, error) {
+	value, ok := r.vars[name]
+	if !ok {
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	return value, nil
+}
+
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
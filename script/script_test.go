@@ -0,0 +1,93 @@
+package script
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunner(t *testing.T) {
+	source := `# Reset the event world
+set world event
+say Resetting $world in ${delay}s, it costs $$5
+sleep $delay
+confirm "Restore $1?"
+
+on-error continue
+!restore $1
+`
+	s, err := Parse("reset.mcs", strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := s.Runner([]string{"latest"}, map[string]string{"delay": "10"})
+
+	expected := []Step{
+		{Kind: Command, Line: 3, Command: "say Resetting event in 10s, it costs $5"},
+		{Kind: Sleep, Line: 4, Sleep: 10 * time.Second},
+		{Kind: Confirm, Line: 5, Prompt: "Restore latest?"},
+		{Kind: Command, Line: 8, Command: "!restore latest"},
+	}
+	for _, e := range expected {
+		step, ok, err := r.Next()
+		if err != nil || !ok {
+			t.Fatalf("Expected %v, got %v %v", e, ok, err)
+		}
+		step.text = ""
+		if step != e {
+			t.Errorf("Expected %+v, got %+v", e, step)
+		}
+	}
+	if !r.ContinueOnError() {
+		t.Errorf("Expected on-error continue")
+	}
+	if _, ok, _ := r.Next(); ok {
+		t.Errorf("Expected the end of the script")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, source := range []string{
+		"sleep",
+		"sleep forever",
+		"sleep -5",
+		"sleep -5s",
+		"confirm Go on?",
+		"set 1x 2",
+		"on-error maybe",
+	} {
+		if _, err := Parse("bad.mcs", strings.NewReader(source)); err == nil {
+			t.Errorf("%q: expected error", source)
+		}
+	}
+
+	for _, source := range []string{"say $missing", "say $1", "sleep $1"} {
+		s, err := Parse("bad.mcs", strings.NewReader(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := s.Runner(nil, nil).Next(); err == nil {
+			t.Errorf("%q: expected error", source)
+		}
+	}
+}
+
+// Steps are sent to the server, the TUI commands would become server tasks
+func TestClientCommands(t *testing.T) {
+	_, err := Parse("export.mcs", strings.NewReader("say saving\n!backup\n!export"))
+	if err == nil || err.Error() != "export.mcs:3: !export only works when typed in the prompt" {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	s, err := Parse("restore.mcs", strings.NewReader("!restore latest\nset screen !players\n$screen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := s.Runner(nil, nil)
+	if step, _, err := r.Next(); err != nil || step.Command != "!restore latest" {
+		t.Errorf("Expected !restore with a name to run, got %v", err)
+	}
+	if _, _, err := r.Next(); err == nil || !strings.HasPrefix(err.Error(), "restore.mcs:3:") {
+		t.Errorf("Expected the variable to be checked, got %v", err)
+	}
+}
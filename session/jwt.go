@@ -0,0 +1,53 @@
+package session
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tokens about to expire are not worth using
+const expiryLeeway = 30 * time.Second
+
+// Returns the time in the exp claim of a JWT
+// The signature is not verified, only the server can do it
+func Expiry(token string) (time.Time, bool, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false, fmt.Errorf("token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("can't decode token payload: %w", err)
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, false, fmt.Errorf("can't parse token claims: %w", err)
+	}
+	if claims.Exp == nil {
+		return time.Time{}, false, nil
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid exp claim: %w", err)
+	}
+	return time.Unix(int64(exp), 0), true, nil
+}
+
+// Returns true if the token can still be used
+// Tokens without exp are valid until the server says otherwise
+func Valid(token string, now time.Time) bool {
+	exp, ok, err := Expiry(token)
+	if err != nil {
+		return false
+	}
+	if !ok {
+		return true
+	}
+	return now.Add(expiryLeeway).Before(exp)
+}
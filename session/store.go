@@ -0,0 +1,175 @@
+// Package session keeps the JWT tokens between runs,
+// so users don't need to login every time
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"mctui/config"
+)
+
+// A token from a previous login
+type Entry struct {
+	Host     string    `json:"host"`
+	Port     int       `json:"port"`
+	Username string    `json:"username"`
+	Token    string    `json:"token"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// File with one token per host/port/user
+// Only the owner can read it
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Opens the store in the config directory
+func Default() (*Store, error) {
+	path, err := config.Path("sessions.json")
+	if err != nil {
+		return nil, err
+	}
+	return Open(path), nil
+}
+
+func key(host string, port int, username string) string {
+	return fmt.Sprintf("%s@%s:%d", username, host, port)
+}
+
+// Stores the token, replacing the previous one for the same user
+func (s *Store) Save(host string, port int, username, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	entries[key(host, port, username)] = Entry{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Token:    token,
+		SavedAt:  time.Now(),
+	}
+	return s.write(entries)
+}
+
+// Returns the token of username
+// If username is empty, returns the most recent token for the server
+func (s *Store) Load(host string, port int, username string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if username != "" {
+		entry, ok := entries[key(host, port, username)]
+		return entry, ok, nil
+	}
+
+	var latest Entry
+	var found bool
+	for _, entry := range entries {
+		if entry.Host != host || entry.Port != port {
+			continue
+		}
+		if !found || entry.SavedAt.After(latest.SavedAt) {
+			latest = entry
+			found = true
+		}
+	}
+	return latest, found, nil
+}
+
+// Returns the entry with this token, wherever it is
+func (s *Store) Find(token string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, entry := range entries {
+		if entry.Token == token {
+			return entry, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// Removes the token of username
+// If username is empty, removes every token for the server
+func (s *Store) Delete(host string, port int, username string) error {
+	return s.deleteFunc(func(entry Entry) bool {
+		return entry.Host == host && entry.Port == port &&
+			(username == "" || entry.Username == username)
+	})
+}
+
+// Removes the entry with this token, wherever it is
+func (s *Store) DeleteToken(token string) error {
+	return s.deleteFunc(func(entry Entry) bool {
+		return entry.Token == token
+	})
+}
+
+func (s *Store) deleteFunc(match func(Entry) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.read()
+	if err != nil {
+		return err
+	}
+	for k, entry := range entries {
+		if match(entry) {
+			delete(entries, k)
+		}
+	}
+	return s.write(entries)
+}
+
+func (s *Store) read() (map[string]Entry, error) {
+	entries := make(map[string]Entry)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't read sessions: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("can't parse sessions: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *Store) write(entries map[string]Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("can't encode sessions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("can't create sessions directory: %w", err)
+	}
+	// WriteFile keeps the permissions of existing files
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("can't write sessions: %w", err)
+	}
+	return os.Chmod(s.path, 0o600)
+}
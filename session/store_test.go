@@ -0,0 +1,78 @@
+package session
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func makeToken(payload string) string {
+	return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	s := Open(path)
+
+	if _, ok, err := s.Load("localhost", 8090, ""); ok || err != nil {
+		t.Fatalf("Expected empty store, got %v %v", ok, err)
+	}
+
+	s.Save("localhost", 8090, "admin", "token1")
+	s.Save("localhost", 8090, "steve", "token2")
+	s.Save("example.com", 8090, "admin", "token3")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	entry, ok, _ := s.Load("localhost", 8090, "admin")
+	if !ok || entry.Token != "token1" {
+		t.Errorf("Expected token1, got %+v", entry)
+	}
+	entry, ok, _ = s.Load("localhost", 8090, "")
+	if !ok || entry.Token != "token2" {
+		t.Errorf("Expected most recent token2, got %+v", entry)
+	}
+
+	s.DeleteToken("token2")
+	entry, _, _ = s.Load("localhost", 8090, "")
+	if entry.Token != "token1" {
+		t.Errorf("Expected token1 after delete, got %+v", entry)
+	}
+
+	s.Delete("localhost", 8090, "")
+	if _, ok, _ := s.Load("localhost", 8090, ""); ok {
+		t.Errorf("Expected no tokens for localhost")
+	}
+	if _, ok, _ := s.Load("example.com", 8090, "admin"); !ok {
+		t.Errorf("Expected token for example.com to be kept")
+	}
+}
+
+func TestValid(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		token    string
+		expected bool
+	}{
+		{makeToken(`{"sub":"admin","exp":1700003600}`), true},
+		{makeToken(`{"sub":"admin","exp":1699999000}`), false},
+		// About to expire
+		{makeToken(`{"sub":"admin","exp":1700000010}`), false},
+		{makeToken(`{"sub":"admin"}`), true},
+		{"not a jwt", false},
+	}
+
+	for _, tc := range tests {
+		if result := Valid(tc.token, now); result != tc.expected {
+			t.Errorf("Valid(%s): expected %v, got %v", tc.token, tc.expected, result)
+		}
+	}
+}
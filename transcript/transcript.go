@@ -0,0 +1,132 @@
+// Package transcript writes the commands of a session to a file,
+// so they can be attached to reports
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	Markdown Format = "markdown"
+	Text     Format = "text"
+	// One JSON object per line
+	JSON Format = "json"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// A command or task and its output
+type Entry struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	// When it was sent
+	Time   time.Time `json:"time"`
+	Task   bool      `json:"task"`
+	Failed bool      `json:"failed"`
+	// HTTP status, 0 for client side commands and unreachable servers
+	Status     int   `json:"status"`
+	DurationMS int64 `json:"duration_ms"`
+}
+
+// e.g. 2024-05-06 07:08:09 • task • ok • 200 • 35ms
+func (e Entry) details() string {
+	kind := "command"
+	if e.Task {
+		kind = "task"
+	}
+	status := "ok"
+	if e.Failed {
+		status = "failed"
+	}
+	parts := []string{e.Time.Format(timeLayout), kind, status}
+	if e.Status != 0 {
+		parts = append(parts, strconv.Itoa(e.Status))
+	}
+	if e.DurationMS > 0 {
+		parts = append(parts, fmt.Sprintf("%dms", e.DurationMS))
+	}
+	return strings.Join(parts, " • ")
+}
+
+// Picks the format from the extension
+// .md is Markdown, .json and .jsonl are JSON lines, anything else is text
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return Markdown
+	case ".json", ".jsonl":
+		return JSON
+	default:
+		return Text
+	}
+}
+
+// Writes the entries to path, in the format of its extension
+// Outputs may have secrets, so only the user can read it
+func WriteFile(path, title string, entries []Entry) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, FormatOf(path), title, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// title is the first line, e.g. the server. JSON lines have no title
+func Write(w io.Writer, format Format, title string, entries []Entry) error {
+	switch format {
+	case Markdown:
+		return writeMarkdown(w, title, entries)
+	case JSON:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Text:
+		return writeText(w, title, entries)
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+}
+
+func writeMarkdown(w io.Writer, title string, entries []Entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n## `%s`\n\n", e.Command)
+		fmt.Fprintf(&b, "%s\n", e.details())
+		if output := strings.TrimRight(e.Output, "\n"); output != "" {
+			fmt.Fprintf(&b, "\n```\n%s\n```\n", output)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeText(w io.Writer, title string, entries []Entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", title)
+	for _, e := range entries {
+		fmt.Fprintf(&b, "\n> %s\n# %s\n", e.Command, e.details())
+		if output := strings.TrimRight(e.Output, "\n"); output != "" {
+			b.WriteString(output + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
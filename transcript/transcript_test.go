@@ -0,0 +1,65 @@
+package transcript
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	entries := []Entry{
+		{Command: "list", Output: "There are 0 of a max of 20 players online: ", Time: at, Status: 200, DurationMS: 35},
+		{Command: "!backup", Output: "500 disk full", Time: at, Task: true, Failed: true, Status: 500, DurationMS: 1200},
+		{Command: "!export", Output: "", Time: at},
+	}
+
+	tests := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format: Markdown,
+			expected: "# survival\n" +
+				"\n## `list`\n\n2024-05-06 07:08:09 • command • ok • 200 • 35ms\n\n```\nThere are 0 of a max of 20 players online: \n```\n" +
+				"\n## `!backup`\n\n2024-05-06 07:08:09 • task • failed • 500 • 1200ms\n\n```\n500 disk full\n```\n" +
+				"\n## `!export`\n\n2024-05-06 07:08:09 • command • ok\n",
+		},
+		{
+			format: Text,
+			expected: "survival\n" +
+				"\n> list\n# 2024-05-06 07:08:09 • command • ok • 200 • 35ms\nThere are 0 of a max of 20 players online: \n" +
+				"\n> !backup\n# 2024-05-06 07:08:09 • task • failed • 500 • 1200ms\n500 disk full\n" +
+				"\n> !export\n# 2024-05-06 07:08:09 • command • ok\n",
+		},
+		{
+			format: JSON,
+			expected: `{"command":"list","output":"There are 0 of a max of 20 players online: ","time":"2024-05-06T07:08:09Z","task":false,"failed":false,"status":200,"duration_ms":35}` + "\n" +
+				`{"command":"!backup","output":"500 disk full","time":"2024-05-06T07:08:09Z","task":true,"failed":true,"status":500,"duration_ms":1200}` + "\n" +
+				`{"command":"!export","output":"","time":"2024-05-06T07:08:09Z","task":false,"failed":false,"status":0,"duration_ms":0}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		var out strings.Builder
+		if err := Write(&out, tc.format, "survival", entries); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != tc.expected {
+			t.Errorf("%s:\nExpected:\n%s\nGot:\n%s", tc.format, tc.expected, out.String())
+		}
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, expected := range map[string]Format{
+		"report.md":    Markdown,
+		"report.JSONL": JSON,
+		"report.txt":   Text,
+		"report":       Text,
+	} {
+		if format := FormatOf(path); format != expected {
+			t.Errorf("%s: expected %s, got %s", path, expected, format)
+		}
+	}
+}